@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	_ "net/http/pprof"
 
 	"fmt"
@@ -16,28 +17,64 @@ import (
 
 	"github.com/consensus-shipyard/uptime-checker/uptime"
 	lcli "github.com/filecoin-project/lotus/cli"
+	"github.com/filecoin-project/lotus/api/v0api"
 
 	"github.com/libp2p/go-libp2p"
 	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
+	"github.com/libp2p/go-libp2p-core/crypto"
 	"github.com/libp2p/go-libp2p-core/host"
 	"github.com/multiformats/go-multiaddr"
 	peerstore "github.com/libp2p/go-libp2p-core/peer"
 
 	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/mitchellh/go-homedir"
 )
 
 var log = logging.Logger("uptime-checker")
 
 const MultiAddressDelimiter = ","
 
+var fromFlag = &cli.StringFlag{
+	Name:    "from",
+	EnvVars: []string{"FROM"},
+	Usage:   "The address of the wallet signing the messages, which must be in the node",
+	Value:   "",
+}
+
+var actorIdFlag = &cli.IntFlag{
+	Name:    "actor-id",
+	EnvVars: []string{"ACTOR_ID"},
+	Usage:   "The actor id the wallet signing the messages must resolve to, not checked if not set",
+}
+
+var identityFlag = &cli.StringFlag{
+	Name:    "identity-path",
+	EnvVars: []string{"IDENTITY_PATH"},
+	Usage:   "The file of the libp2p key of the checker, generated if missing, which its peer id is derived from",
+	Value:   "~/.uptime-checker/identity",
+}
+
+var probeFirstFlag = &cli.BoolFlag{
+	Name:  "probe-first",
+	Usage: "Probe the multi-addresses as the checkers would and refuse to send the message if any is not reachable",
+}
+
+var dryRunFlag = &cli.BoolFlag{
+	Name:  "dry-run",
+	Usage: "Simulate the messages to the actor against the current state without sending them",
+}
+
 func main() {
 	local := []*cli.Command{
 		runCmd,
 		newMemberCmd,
 		editMemberCmd,
 		rmMemberCmd,
+		registerCheckerCmd,
 		editCheckerCmd,
 		rmCheckerCmd,
+		diffCmd,
 		versionCmd,
 	}
 
@@ -94,7 +131,7 @@ var runCmd = &cli.Command{
 		&cli.IntFlag{
 			Name:    "actor-id",
 			EnvVars: []string{"ACTOR_ID"},
-			Usage:   "The actor id of the checker",
+			Usage:   "The actor id of the checker, resolved from the wallet if not set, which it must match if set",
 			Value:   0,
 		},
 		&cli.StringFlag{
@@ -109,6 +146,17 @@ var runCmd = &cli.Command{
 			Usage:   "The up time checker libp2p port",
 			Value:   "30000",
 		},
+		&cli.StringFlag{
+			Name:    "announce-addrs",
+			EnvVars: []string{"ANNOUNCE_ADDRS"},
+			Usage:   "The comma seperated public multi-addresses registered for the checker, instead of its listen addresses",
+			Value:   "",
+		},
+		&cli.BoolFlag{
+			Name:    "no-auto-register",
+			EnvVars: []string{"NO_AUTO_REGISTER"},
+			Usage:   "Do not register the checker on start if not registered, see register-checker",
+		},
 		&cli.StringFlag{
 			Name:    "node-info-port",
 			EnvVars: []string{"NODE_INFO_PORT"},
@@ -118,9 +166,54 @@ var runCmd = &cli.Command{
 		&cli.IntFlag{
 			Name:    "wallet-index",
 			EnvVars: []string{"WALLET_INDEX"},
-			Usage:   "The index of wallet to use",
+			Usage:   "The index of wallet to use when --from is not set, deprecated as the order of the wallets is not stable",
+			Value:   0,
+		},
+		&cli.IntFlag{
+			Name:    "probe-concurrency",
+			EnvVars: []string{"PROBE_CONCURRENCY"},
+			Usage:   "The max number of nodes probed in parallel",
+			Value:   uptime.DEFAULT_PROBE_CONCURRENCY,
+		},
+		&cli.DurationFlag{
+			Name:    "round-timeout",
+			EnvVars: []string{"ROUND_TIMEOUT"},
+			Usage:   "The max duration of a probing round, longer than the probe timeout so that hung nodes time out within the round",
+			Value:   uptime.DEFAULT_ROUND_TIMEOUT,
+		},
+		&cli.IntFlag{
+			Name:    "confidence",
+			EnvVars: []string{"CONFIDENCE"},
+			Usage:   "The number of epochs behind the head of the state checkers are reported on",
+			Value:   uptime.DEFAULT_CONFIDENCE,
+		},
+		&cli.DurationFlag{
+			Name:    "latency-threshold",
+			EnvVars: []string{"LATENCY_THRESHOLD"},
+			Usage:   "The latency above which member addresses are reported slow in the events, 0 to disable",
 			Value:   0,
 		},
+		&cli.StringFlag{
+			Name:    "history-path",
+			EnvVars: []string{"HISTORY_PATH"},
+			Usage:   "The directory of the probe history store, empty to disable",
+			Value:   "~/.uptime-checker/history",
+		},
+		&cli.DurationFlag{
+			Name:    "history-retention",
+			EnvVars: []string{"HISTORY_RETENTION"},
+			Usage:   "How long the downsampled probe history is kept",
+			Value:   uptime.DEFAULT_HISTORY_RETENTION,
+		},
+		&cli.DurationFlag{
+			Name:    "history-raw-retention",
+			EnvVars: []string{"HISTORY_RAW_RETENTION"},
+			Usage:   "How long the raw probe results are kept before being downsampled",
+			Value:   uptime.DEFAULT_HISTORY_RAW_RETENTION,
+		},
+		identityFlag,
+		fromFlag,
+		dryRunFlag,
 	},
 	Action: func(cctx *cli.Context) error {
 		ctx := context.Background()
@@ -138,6 +231,7 @@ var runCmd = &cli.Command{
 			"host", checkerHost,
 			"port", checkerPort,
 			"nodeInfoPort", nodeInfoPort,
+			"from", cctx.String("from"),
 			"walletIndex", walletIndex,
 		)
 
@@ -147,7 +241,12 @@ var runCmd = &cli.Command{
 		}
 		defer closer()
 
-		node, ping, addrs, err := setupLibp2p(checkerHost, checkerPort)
+		identity, err := loadIdentity(cctx)
+		if err != nil {
+			return err
+		}
+
+		node, ping, addrs, err := setupLibp2p(checkerHost, checkerPort, identity)
 		if err != nil {
 			return err
 		}
@@ -157,16 +256,75 @@ var runCmd = &cli.Command{
 			multiAddresses[i] = addr.String()
 		}
 
+		// the listen addresses may not be reachable from outside, e.g. behind a nat
+		if announceAddrs := cctx.String("announce-addrs"); announceAddrs != "" {
+			multiAddresses = strings.Split(announceAddrs, MultiAddressDelimiter)
+
+			probers := uptime.NewDefaultProberRegistry(node, ping)
+			for _, addr := range multiAddresses {
+				if err := probers.Validate(addr, node.ID().String()); err != nil {
+					return err
+				}
+			}
+		} else {
+			multiAddresses = uptime.PublicAddrs(multiAddresses)
+			if len(multiAddresses) == 0 {
+				return fmt.Errorf("%w: the listen addresses are local, set --announce-addrs", uptime.ErrNoAnnounceAddrs)
+			}
+		}
+
 		checker, err := uptime.NewUptimeChecker(api, actorAddress, multiAddresses, self, walletIndex, node, ping)
+		if err != nil {
+			return err
+		}
+		checker.SetProbeConcurrency(cctx.Int("probe-concurrency"))
+		checker.SetRoundTimeout(cctx.Duration("round-timeout"))
+		checker.SetLatencyThreshold(cctx.Duration("latency-threshold"))
+		checker.SetConfidence(abi.ChainEpoch(cctx.Int("confidence")))
+		checker.SetDryRun(cctx.Bool("dry-run"))
+		checker.SetAutoRegister(!cctx.Bool("no-auto-register"))
+
+		wallet, err := uptime.ResolveWallet(ctx, api, cctx.String("from"), walletIndex)
+		if err != nil {
+			return err
+		}
+		checker.SetWallet(wallet)
+
+		if historyPath := cctx.String("history-path"); historyPath != "" {
+			historyPath, err := homedir.Expand(historyPath)
+			if err != nil {
+				return err
+			}
+
+			history, err := uptime.OpenHistoryStore(
+				historyPath,
+				cctx.Duration("history-retention"),
+				cctx.Duration("history-raw-retention"),
+			)
+			if err != nil {
+				return err
+			}
+			defer history.Close()
+
+			checker.SetHistoryStore(history)
+		}
+
 		err = checker.Start(ctx)
 		if err != nil {
 			return err
 		}
 
 		http.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
-			info, _ := checker.NodeInfoJsonString()
+			info, err := checker.NodeInfoJsonString()
+			if err != nil {
+				log.Errorw("cannot encode node info", "err", err)
+				http.Error(writer, err.Error(), http.StatusInternalServerError)
+				return
+			}
 			fmt.Fprint(writer, info)
 		})
+		http.Handle("/v1/", uptime.NewAPIHandler(&checker))
+		http.Handle("/metrics", uptime.MetricsHandler())
 		err = http.ListenAndServe(":" + nodeInfoPort, nil)
 		if err != nil {
 			panic(err)
@@ -201,12 +359,16 @@ var newMemberCmd = &cli.Command{
 			Usage:   "The comma seperated multi-addresses to be registered",
 			Value:   "",
 		},
+		probeFirstFlag,
 		&cli.IntFlag{
 			Name:    "wallet-index",
 			EnvVars: []string{"WALLET_INDEX"},
-			Usage:   "The index of wallet to use",
+			Usage:   "The index of wallet to use when --from is not set, deprecated as the order of the wallets is not stable",
 			Value:   0,
 		},
+		fromFlag,
+		actorIdFlag,
+		dryRunFlag,
 	},
 	Action: func(cctx *cli.Context) error {
 		ctx := context.Background()
@@ -223,17 +385,15 @@ var newMemberCmd = &cli.Command{
 
 		log.Infow(
 			"upsert node to uptime checker",
+			"from", cctx.String("from"),
 			"walletIndex", walletIndex,
 			"actorAddress", actorAddress,
 			"multiAddresses", multiAddressRaw,
 			"peerId", peerId,
 		)
 
-		for _, addr := range multiAddressRaw {
-			_, err := address.NewFromString(addr)
-			if err != nil {
-				return err
-			}
+		if err := validateMultiAddrs(ctx, cctx, multiAddressRaw, peerId); err != nil {
+			return err
 		}
 
 		api, closer, err := lcli.GetFullNodeAPI(cctx)
@@ -242,7 +402,12 @@ var newMemberCmd = &cli.Command{
 		}
 		defer closer()
 
-		err = uptime.NewMember(ctx, api, actorAddress, multiAddressRaw, peerId, walletIndex)
+		client, err := newActorClient(ctx, cctx, api, actorAddress)
+		if err != nil {
+			return err
+		}
+
+		err = client.NewMember(ctx, uptime.NodeInfoPayload{Id: peerId, Addresses: multiAddressRaw})
 		if err != nil {
 			return err
 		}
@@ -278,12 +443,16 @@ var editMemberCmd = &cli.Command{
 			Usage:   "The comma seperated multi-addresses to be registered",
 			Value:   "",
 		},
+		probeFirstFlag,
 		&cli.IntFlag{
 			Name:    "wallet-index",
 			EnvVars: []string{"WALLET_INDEX"},
-			Usage:   "The index of wallet to use",
+			Usage:   "The index of wallet to use when --from is not set, deprecated as the order of the wallets is not stable",
 			Value:   0,
 		},
+		fromFlag,
+		actorIdFlag,
+		dryRunFlag,
 	},
 	Action: func(cctx *cli.Context) error {
 		ctx := context.Background()
@@ -300,17 +469,113 @@ var editMemberCmd = &cli.Command{
 
 		log.Infow(
 			"edits member in uptime checker",
+			"from", cctx.String("from"),
 			"walletIndex", walletIndex,
 			"actorAddress", actorAddress,
 			"multiAddresses", multiAddressRaw,
 			"peerId", peerId,
 		)
 
-		for _, addr := range multiAddressRaw {
-			_, err := address.NewFromString(addr)
+		if err := validateMultiAddrs(ctx, cctx, multiAddressRaw, peerId); err != nil {
+			return err
+		}
+
+		api, closer, err := lcli.GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		client, err := newActorClient(ctx, cctx, api, actorAddress)
+		if err != nil {
+			return err
+		}
+
+		err = client.EditMember(ctx, uptime.NodeInfoPayload{Id: peerId, Addresses: multiAddressRaw})
+		if err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
+
+var registerCheckerCmd = &cli.Command{
+	Name:  "register-checker",
+	Usage: "Registers a checker node to the uptime checker actor with its public multi-addresses.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "actor-address",
+			EnvVars: []string{"ACTOR_ADDRESS"},
+			Usage:   "The address of the up time checker FVM actor",
+			Value:   "",
+		},
+		&cli.StringFlag{
+			Name:    "peer-id",
+			Usage:   "The peer id of the checker, which must be the one of its identity if set",
+			Value:   "",
+		},
+		&cli.StringFlag{
+			Name:    "multi-addresses",
+			Usage:   "The comma seperated public multi-addresses of the checker, reachable by the other checkers",
+			Value:   "",
+		},
+		probeFirstFlag,
+		identityFlag,
+		&cli.IntFlag{
+			Name:    "wallet-index",
+			EnvVars: []string{"WALLET_INDEX"},
+			Usage:   "The index of wallet to use when --from is not set, deprecated as the order of the wallets is not stable",
+			Value:   0,
+		},
+		fromFlag,
+		actorIdFlag,
+		dryRunFlag,
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := context.Background()
+
+		walletIndex := cctx.Int("wallet-index")
+
+		actorAddress, err := address.NewFromString(cctx.String("actor-address"))
+		if err != nil {
+			return err
+		}
+
+		multiAddressRaw := strings.Split(cctx.String("multi-addresses"), MultiAddressDelimiter)
+
+		// the peer id the checker runs with, so that the other checkers probe this node
+		identity, err := loadIdentity(cctx)
+		if err != nil {
+			return err
+		}
+		nodeId, err := peerstore.IDFromPrivateKey(identity)
+		if err != nil {
+			return err
+		}
+
+		peerId := nodeId.String()
+		if flagId := cctx.String("peer-id"); flagId != "" {
+			decoded, err := peerstore.Decode(flagId)
 			if err != nil {
 				return err
 			}
+			if decoded != nodeId {
+				return fmt.Errorf("peer id %s is not the one of the identity %s, %s", flagId, cctx.String("identity-path"), nodeId)
+			}
+		}
+
+		log.Infow(
+			"registers checker in uptime checker",
+			"from", cctx.String("from"),
+			"walletIndex", walletIndex,
+			"actorAddress", actorAddress,
+			"multiAddresses", multiAddressRaw,
+			"peerId", peerId,
+		)
+
+		if err := validateMultiAddrs(ctx, cctx, multiAddressRaw, peerId); err != nil {
+			return err
 		}
 
 		api, closer, err := lcli.GetFullNodeAPI(cctx)
@@ -319,11 +584,23 @@ var editMemberCmd = &cli.Command{
 		}
 		defer closer()
 
-		err = uptime.EditMember(ctx, api, actorAddress, multiAddressRaw, peerId, walletIndex)
+		client, err := newActorClient(ctx, cctx, api, actorAddress)
 		if err != nil {
 			return err
 		}
 
+		err = client.NewChecker(ctx, uptime.NodeInfoPayload{Id: peerId, Addresses: multiAddressRaw})
+		if err != nil {
+			return err
+		}
+
+		log.Infow(
+			"registered checker",
+			"actorAddress", actorAddress,
+			"multiAddresses", multiAddressRaw,
+			"peerId", peerId,
+		)
+
 		return nil
 	},
 }
@@ -348,12 +625,16 @@ var editCheckerCmd = &cli.Command{
 			Usage:   "The comma seperated multi-addresses to be registered",
 			Value:   "",
 		},
+		probeFirstFlag,
 		&cli.IntFlag{
 			Name:    "wallet-index",
 			EnvVars: []string{"WALLET_INDEX"},
-			Usage:   "The index of wallet to use",
+			Usage:   "The index of wallet to use when --from is not set, deprecated as the order of the wallets is not stable",
 			Value:   0,
 		},
+		fromFlag,
+		actorIdFlag,
+		dryRunFlag,
 	},
 	Action: func(cctx *cli.Context) error {
 		ctx := context.Background()
@@ -370,17 +651,15 @@ var editCheckerCmd = &cli.Command{
 
 		log.Infow(
 			"edits checker in uptime checker",
+			"from", cctx.String("from"),
 			"walletIndex", walletIndex,
 			"actorAddress", actorAddress,
 			"multiAddresses", multiAddressRaw,
 			"peerId", peerId,
 		)
 
-		for _, addr := range multiAddressRaw {
-			_, err := address.NewFromString(addr)
-			if err != nil {
-				return err
-			}
+		if err := validateMultiAddrs(ctx, cctx, multiAddressRaw, peerId); err != nil {
+			return err
 		}
 
 		api, closer, err := lcli.GetFullNodeAPI(cctx)
@@ -389,7 +668,12 @@ var editCheckerCmd = &cli.Command{
 		}
 		defer closer()
 
-		err = uptime.EditChecker(ctx, api, actorAddress, multiAddressRaw, peerId, walletIndex)
+		client, err := newActorClient(ctx, cctx, api, actorAddress)
+		if err != nil {
+			return err
+		}
+
+		err = client.EditChecker(ctx, uptime.NodeInfoPayload{Id: peerId, Addresses: multiAddressRaw})
 		if err != nil {
 			return err
 		}
@@ -411,9 +695,12 @@ var rmCheckerCmd = &cli.Command{
 		&cli.IntFlag{
 			Name:    "wallet-index",
 			EnvVars: []string{"WALLET_INDEX"},
-			Usage:   "The index of wallet to use",
+			Usage:   "The index of wallet to use when --from is not set, deprecated as the order of the wallets is not stable",
 			Value:   0,
 		},
+		fromFlag,
+		actorIdFlag,
+		dryRunFlag,
 	},
 	Action: func(cctx *cli.Context) error {
 		ctx := context.Background()
@@ -427,6 +714,7 @@ var rmCheckerCmd = &cli.Command{
 
 		log.Infow(
 			"removes checker in uptime checker",
+			"from", cctx.String("from"),
 			"walletIndex", walletIndex,
 			"actorAddress", actorAddress,
 		)
@@ -437,7 +725,12 @@ var rmCheckerCmd = &cli.Command{
 		}
 		defer closer()
 
-		err = uptime.RmChecker(ctx, api, actorAddress, walletIndex)
+		client, err := newActorClient(ctx, cctx, api, actorAddress)
+		if err != nil {
+			return err
+		}
+
+		err = client.RmChecker(ctx)
 		if err != nil {
 			return err
 		}
@@ -459,9 +752,12 @@ var rmMemberCmd = &cli.Command{
 		&cli.IntFlag{
 			Name:    "wallet-index",
 			EnvVars: []string{"WALLET_INDEX"},
-			Usage:   "The index of wallet to use",
+			Usage:   "The index of wallet to use when --from is not set, deprecated as the order of the wallets is not stable",
 			Value:   0,
 		},
+		fromFlag,
+		actorIdFlag,
+		dryRunFlag,
 	},
 	Action: func(cctx *cli.Context) error {
 		ctx := context.Background()
@@ -475,6 +771,7 @@ var rmMemberCmd = &cli.Command{
 
 		log.Infow(
 			"removes member in uptime checker",
+			"from", cctx.String("from"),
 			"walletIndex", walletIndex,
 			"actorAddress", actorAddress,
 		)
@@ -485,7 +782,12 @@ var rmMemberCmd = &cli.Command{
 		}
 		defer closer()
 
-		err = uptime.RmMember(ctx, api, actorAddress, walletIndex)
+		client, err := newActorClient(ctx, cctx, api, actorAddress)
+		if err != nil {
+			return err
+		}
+
+		err = client.RmMember(ctx)
 		if err != nil {
 			return err
 		}
@@ -494,11 +796,144 @@ var rmMemberCmd = &cli.Command{
 	},
 }
 
-func setupLibp2p(checkerHost string, checkerPort string) (host.Host, *ping.PingService, []multiaddr.Multiaddr, error) {
-	node, err := libp2p.New(
+var diffCmd = &cli.Command{
+	Name:  "diff",
+	Usage: "Print the changes of the uptime checker actor state between two epochs.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "actor-address",
+			EnvVars: []string{"ACTOR_ADDRESS"},
+			Usage:   "The address of the up time checker FVM actor",
+			Value:   "",
+		},
+		&cli.Int64Flag{
+			Name:     "from",
+			Usage:    "The epoch to diff from",
+			Required: true,
+		},
+		&cli.Int64Flag{
+			Name:  "to",
+			Usage: "The epoch to diff to, the chain head if not set",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		ctx := context.Background()
+
+		actorAddress, err := address.NewFromString(cctx.String("actor-address"))
+		if err != nil {
+			return err
+		}
+
+		api, closer, err := lcli.GetFullNodeAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		head, err := api.ChainHead(ctx)
+		if err != nil {
+			return err
+		}
+
+		to := head
+		if cctx.IsSet("to") {
+			to, err = api.ChainGetTipSetByHeight(ctx, abi.ChainEpoch(cctx.Int64("to")), head.Key())
+			if err != nil {
+				return err
+			}
+		}
+
+		from, err := api.ChainGetTipSetByHeight(ctx, abi.ChainEpoch(cctx.Int64("from")), to.Key())
+		if err != nil {
+			return err
+		}
+
+		diff, err := uptime.DiffAt(ctx, api, actorAddress, from.Key(), to.Key())
+		if err != nil {
+			return err
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diff)
+	},
+}
+
+// newActorClient returns the client of the actor sending from the --from wallet, or the one
+// at --wallet-index, which must be the actor --actor-id if set. It only simulates the
+// messages with --dry-run
+func newActorClient(ctx context.Context, cctx *cli.Context, api v0api.FullNode, actorAddress address.Address) (*uptime.ActorClient, error) {
+	wallet, err := uptime.ResolveWallet(ctx, api, cctx.String("from"), cctx.Int("wallet-index"))
+	if err != nil {
+		return nil, err
+	}
+	if err := uptime.ValidateWallet(ctx, api, wallet); err != nil {
+		return nil, err
+	}
+	if cctx.IsSet("actor-id") {
+		if err := uptime.CheckWalletActor(ctx, api, wallet, uptime.ActorID(cctx.Int("actor-id"))); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Infow("signing with wallet", "wallet", wallet)
+
+	client := uptime.NewActorClient(api, actorAddress, wallet)
+	client.SetDryRun(cctx.Bool("dry-run"))
+	return client, nil
+}
+
+// validateMultiAddrs checks that the checkers can probe the multiaddrs of the node with the
+// peer id, and with --probe-first that they are reachable
+func validateMultiAddrs(ctx context.Context, cctx *cli.Context, addrs []uptime.MultiAddr, peerId string) error {
+	probers := uptime.NewDefaultProberRegistry(nil, nil)
+	for _, addr := range addrs {
+		if err := probers.Validate(addr, peerId); err != nil {
+			return err
+		}
+	}
+
+	if !cctx.Bool("probe-first") {
+		return nil
+	}
+
+	// the libp2p pings need a node, listening on a random port
+	node, ping, _, err := setupLibp2p("0.0.0.0", "0", nil)
+	if err != nil {
+		return err
+	}
+	defer node.Close()
+
+	probers = uptime.NewDefaultProberRegistry(node, ping)
+	for _, addr := range addrs {
+		if err := probers.CheckReachable(ctx, addr); err != nil {
+			return err
+		}
+		log.Infow("multi address reachable", "addr", addr)
+	}
+	return nil
+}
+
+// loadIdentity returns the libp2p key of the checker at --identity-path
+func loadIdentity(cctx *cli.Context) (crypto.PrivKey, error) {
+	identityPath, err := homedir.Expand(cctx.String("identity-path"))
+	if err != nil {
+		return nil, err
+	}
+	return uptime.LoadOrCreateIdentity(identityPath)
+}
+
+// setupLibp2p starts the libp2p node with the identity, a random one if nil
+func setupLibp2p(checkerHost string, checkerPort string, identity crypto.PrivKey) (host.Host, *ping.PingService, []multiaddr.Multiaddr, error) {
+	options := []libp2p.Option{
 		libp2p.ListenAddrStrings("/ip4/" + checkerHost + "/tcp/" + checkerPort),
 		libp2p.Ping(false),
-	)
+	}
+	if identity != nil {
+		options = append(options, libp2p.Identity(identity))
+	}
+
+	node, err := libp2p.New(options...)
 	if err != nil {
 		return node, nil, make([]multiaddr.Multiaddr, 0), err
 	}
@@ -512,14 +947,14 @@ func setupLibp2p(checkerHost string, checkerPort string) (host.Host, *ping.PingS
 	}
 	addrs, err := peerstore.AddrInfoToP2pAddrs(&peerInfo)
 
-	onlyFirst := make([]multiaddr.Multiaddr, 0)
+	ip4Addrs := make([]multiaddr.Multiaddr, 0)
 	for _, addr := range addrs {
 		if strings.HasPrefix(addr.String(), "/ip4") {
-			onlyFirst = append(onlyFirst, addrs[0])
+			ip4Addrs = append(ip4Addrs, addr)
 		}
 	}
 
-	log.Infow("Listen addresses:", "addrs", addrs, "first", onlyFirst)
+	log.Infow("Listen addresses:", "addrs", addrs, "ip4", ip4Addrs)
 
-	return node, pingService, onlyFirst, nil
+	return node, pingService, ip4Addrs, nil
 }
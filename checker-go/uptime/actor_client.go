@@ -0,0 +1,310 @@
+package uptime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	lapi "github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/v0api"
+	chainTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+)
+
+// Exit codes of the actor, see Error::code in the actor
+const EXIT_CODE_ALREADY_VOTED = 10002
+const EXIT_CODE_CANNOT_DESERIALIZE = 10003
+const EXIT_CODE_HAMT = 10004
+const EXIT_CODE_INTERNAL = 10005
+const EXIT_CODE_NO_STATE = 10006
+const EXIT_CODE_ENCODING = 10007
+const EXIT_CODE_ADDRESS = 10008
+const EXIT_CODE_NOT_OWNER = 10009
+const EXIT_CODE_NOT_EXISTS = 10010
+const EXIT_CODE_NOT_CALLER = 10011
+
+// Errors of the actor, ActorError unwraps to them for the matching exit codes
+var ErrAlreadyVoted = errors.New("already voted for the checker")
+var ErrCannotDeserialize = errors.New("actor cannot deserialize the params")
+var ErrHamt = errors.New("actor hamt error")
+var ErrActorInternal = errors.New("actor internal error")
+var ErrNoState = errors.New("actor has no state")
+var ErrEncoding = errors.New("actor encoding error")
+var ErrAddress = errors.New("actor address error")
+var ErrNotOwner = errors.New("not the creator of the node")
+var ErrNotExists = errors.New("node does not exist")
+var ErrNotCaller = errors.New("caller is not a registered checker")
+
+var actorErrors = map[exitcode.ExitCode]error{
+	EXIT_CODE_ALREADY_VOTED:      ErrAlreadyVoted,
+	EXIT_CODE_CANNOT_DESERIALIZE: ErrCannotDeserialize,
+	EXIT_CODE_HAMT:               ErrHamt,
+	EXIT_CODE_INTERNAL:           ErrActorInternal,
+	EXIT_CODE_NO_STATE:           ErrNoState,
+	EXIT_CODE_ENCODING:           ErrEncoding,
+	EXIT_CODE_ADDRESS:            ErrAddress,
+	EXIT_CODE_NOT_OWNER:          ErrNotOwner,
+	EXIT_CODE_NOT_EXISTS:         ErrNotExists,
+	EXIT_CODE_NOT_CALLER:         ErrNotCaller,
+}
+
+// ActorError is the failure of a message executed by the actor. It unwraps to the typed error
+// of the exit code, e.g. errors.Is(err, ErrAlreadyVoted).
+type ActorError struct {
+	Method   abi.MethodNum
+	ExitCode exitcode.ExitCode
+	MsgCid   cid.Cid
+	GasUsed  int64
+	// Abort message of the actor from the execution trace, empty if not available
+	Message string
+	// Whether the failure comes from the simulation of the message, which was not sent
+	Simulated bool
+
+	err error // the typed error of the exit code, nil for unknown codes
+}
+
+func (e *ActorError) Error() string {
+	s := fmt.Sprintf("%s failed with exit code %d (message %s, gas used %d)", methodName(e.Method), e.ExitCode, e.MsgCid, e.GasUsed)
+	if e.Simulated {
+		s = fmt.Sprintf("%s would fail with exit code %d (simulated, gas used %d)", methodName(e.Method), e.ExitCode, e.GasUsed)
+	}
+	if e.err != nil {
+		s = fmt.Sprintf("%s: %s", e.err, s)
+	}
+	if e.Message != "" {
+		s = fmt.Sprintf("%s: %s", s, e.Message)
+	}
+	return s
+}
+
+func (e *ActorError) Unwrap() error {
+	return e.err
+}
+
+// InitParams are the params of the constructor of the actor
+type InitParams struct {
+	Ids       []PeerID      `json:"ids"`
+	Creators  []ActorID     `json:"creators"`
+	Addresses [][]MultiAddr `json:"addresses"`
+	// The actor defaults to DEFAULT_VOTING_DURATION when nil
+	VotingDuration *ChainEpoch `json:"voting_duration"`
+}
+
+// NodeInfoPayload are the params registering or editing a member or checker, its creator
+// being the sender of the message
+type NodeInfoPayload struct {
+	Id        PeerID      `json:"id"`
+	Addresses []MultiAddr `json:"addresses"`
+}
+
+type PeerReportPayload struct {
+	Checker ActorID `json:"checker"`
+}
+
+// ActorClient sends the messages of the uptime checker actor from a wallet, one method per
+// entry point of the actor. The methods wait for the message to be executed and return the
+// typed error matching the exit code of the actor; the actor returns no value. Messages are
+// pushed through a MessageQueue, which tracks them and reprices them if stuck.
+//
+// Messages are simulated against the current state before being sent, those that would
+// abort are not sent.
+type ActorClient struct {
+	api   v0api.FullNode
+	actor address.Address
+	from  address.Address
+
+	queue *MessageQueue // pushes and tracks the messages, may be shared with other clients
+
+	preflight bool // whether messages are simulated before being sent
+	dryRun    bool // whether messages are only simulated
+}
+
+func NewActorClient(api v0api.FullNode, actor address.Address, from address.Address) *ActorClient {
+	return &ActorClient{
+		api:       api,
+		actor:     actor,
+		from:      from,
+		queue:     NewMessageQueue(api),
+		preflight: true,
+	}
+}
+
+// NewActorClientFromIndex returns the client sending from the wallet at the index
+func NewActorClientFromIndex(ctx context.Context, api v0api.FullNode, actor address.Address, walletIndex int) (*ActorClient, error) {
+	wallet, err := getWalletAddressFromIndex(api, ctx, walletIndex)
+	if err != nil {
+		return nil, err
+	}
+	return NewActorClient(api, actor, wallet), nil
+}
+
+// SetQueue sets the queue the messages are pushed through, so that clients sending from the
+// same wallet share its nonces
+func (c *ActorClient) SetQueue(queue *MessageQueue) {
+	c.queue = queue
+}
+
+// SetPreflight sets whether messages are simulated before being sent, the default
+func (c *ActorClient) SetPreflight(preflight bool) {
+	c.preflight = preflight
+}
+
+// SetDryRun sets whether messages are only simulated, never sent
+func (c *ActorClient) SetDryRun(dryRun bool) {
+	c.dryRun = dryRun
+}
+
+// Init calls the constructor of the actor. The actor does not guard it, calling it on a
+// deployed actor resets its state.
+func (c *ActorClient) Init(ctx context.Context, params InitParams) error {
+	return c.call(ctx, INIT_METHOD, params)
+}
+
+func (c *ActorClient) NewChecker(ctx context.Context, params NodeInfoPayload) error {
+	return c.call(ctx, NEW_CHECKER_METHOD, params)
+}
+
+func (c *ActorClient) NewMember(ctx context.Context, params NodeInfoPayload) error {
+	return c.call(ctx, NEW_MEMBER_METHOD, params)
+}
+
+func (c *ActorClient) EditChecker(ctx context.Context, params NodeInfoPayload) error {
+	return c.call(ctx, EDIT_CHECKER_METHOD, params)
+}
+
+func (c *ActorClient) EditMember(ctx context.Context, params NodeInfoPayload) error {
+	return c.call(ctx, EDIT_MEMBER_METHOD, params)
+}
+
+// RmChecker removes the checker created by the sender
+func (c *ActorClient) RmChecker(ctx context.Context) error {
+	return c.call(ctx, RM_CHCKER_METHOD, nil)
+}
+
+// RmMember removes the member created by the sender
+func (c *ActorClient) RmMember(ctx context.Context) error {
+	return c.call(ctx, RM_MEMBER_METHOD, nil)
+}
+
+// ReportChecker votes the checker offline, the sender must be a checker
+func (c *ActorClient) ReportChecker(ctx context.Context, checker ActorID) error {
+	return c.call(ctx, REPORT_CHECKER_METHOD, PeerReportPayload{Checker: checker})
+}
+
+// ReportCheckerAsync sends the vote without waiting for it to be executed, see Await. An
+// identical vote still pending is returned instead of sending another one. The returned
+// message is nil in dry run, the vote being only simulated.
+func (c *ActorClient) ReportCheckerAsync(ctx context.Context, checker ActorID) (*PendingMessage, error) {
+	return c.callAsync(ctx, REPORT_CHECKER_METHOD, PeerReportPayload{Checker: checker})
+}
+
+// Await waits for the message sent by the client to be executed and returns the typed
+// error matching the exit code of the actor
+func (c *ActorClient) Await(ctx context.Context, pending *PendingMessage) error {
+	lookup, err := pending.Wait(ctx)
+	if err != nil {
+		return err
+	}
+	if lookup.Receipt.ExitCode.IsSuccess() {
+		return nil
+	}
+
+	// the message may have been replaced, e.g. repriced, the lookup has the executed one
+	return &ActorError{
+		Method:   pending.Method(),
+		ExitCode: lookup.Receipt.ExitCode,
+		MsgCid:   lookup.Message,
+		GasUsed:  lookup.Receipt.GasUsed,
+		Message:  c.abortMessage(ctx, lookup.TipSet, lookup.Message),
+		err:      actorErrors[lookup.Receipt.ExitCode],
+	}
+}
+
+// call sends the method and waits for it to be executed
+func (c *ActorClient) call(ctx context.Context, method abi.MethodNum, params interface{}) error {
+	pending, err := c.callAsync(ctx, method, params)
+	if err != nil || pending == nil {
+		return err
+	}
+
+	log.Infow("waiting for message to execute...", "method", methodName(method), "cid", pending.Cid())
+	return c.Await(ctx, pending)
+}
+
+// callAsync sends the method with the params json encoded, as parse_params_or_abort expects,
+// through the queue of the client. Methods without params are sent with empty params. The
+// returned message is nil in dry run.
+func (c *ActorClient) callAsync(ctx context.Context, method abi.MethodNum, params interface{}) (*PendingMessage, error) {
+	encoded := make([]byte, 0)
+	if params != nil {
+		var err error
+		if encoded, err = encodeJson(params); err != nil {
+			return nil, err
+		}
+	}
+
+	msg := &chainTypes.Message{
+		To:     c.actor,
+		From:   c.from,
+		Value:  big.Zero(),
+		Method: method,
+		Params: encoded,
+	}
+
+	if c.preflight || c.dryRun {
+		if err := c.simulate(ctx, msg); err != nil {
+			return nil, err
+		}
+		if c.dryRun {
+			return nil, nil
+		}
+	}
+
+	return c.queue.Push(ctx, msg)
+}
+
+// simulate executes the message against the state at the head, returning the ActorError
+// of the message if it would abort
+func (c *ActorClient) simulate(ctx context.Context, msg *chainTypes.Message) error {
+	res, err := c.api.StateCall(ctx, msg, chainTypes.EmptyTSK)
+	if err != nil {
+		return err
+	}
+
+	log.Infow("simulated message", "method", methodName(msg.Method), "exitCode", res.MsgRct.ExitCode, "gasUsed", res.MsgRct.GasUsed)
+	if res.MsgRct.ExitCode.IsSuccess() {
+		return nil
+	}
+
+	return &ActorError{
+		Method:    msg.Method,
+		ExitCode:  res.MsgRct.ExitCode,
+		GasUsed:   res.MsgRct.GasUsed,
+		Message:   invocError(res),
+		Simulated: true,
+		err:       actorErrors[res.MsgRct.ExitCode],
+	}
+}
+
+// abortMessage replays the message to get the abort message of the actor, empty if the
+// replay fails
+func (c *ActorClient) abortMessage(ctx context.Context, tsk chainTypes.TipSetKey, msgCid cid.Cid) string {
+	res, err := c.api.StateReplay(ctx, tsk, msgCid)
+	if err != nil {
+		log.Debugw("cannot replay message", "cid", msgCid, "err", err)
+		return ""
+	}
+	return invocError(res)
+}
+
+// invocError returns the abort message of an invocation, from its execution trace if needed
+func invocError(res *lapi.InvocResult) string {
+	if res.Error != "" {
+		return res.Error
+	}
+	return res.ExecutionTrace.Error
+}
@@ -0,0 +1,101 @@
+package uptime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	peerstore "github.com/libp2p/go-libp2p-core/peer"
+	libp2pMultiaddr "github.com/multiformats/go-multiaddr"
+)
+
+var ErrNoProber = errors.New("no prober supports the multiaddr")
+var ErrMissingPeerID = errors.New("multiaddr has no /p2p/ peer id")
+var ErrPeerIDMismatch = errors.New("multiaddr peer id does not match the node")
+var ErrUnreachable = errors.New("multiaddr is not reachable")
+var ErrUnspecifiedAddr = errors.New("multiaddr has an unspecified ip, it cannot be dialed")
+var ErrNoAnnounceAddrs = errors.New("no public multiaddr to announce")
+
+// Validate checks that the multiaddr can be probed by the checkers: it parses, its ip is not
+// unspecified, its healthcheck suffix or protocols have a prober, and its /p2p/ peer id,
+// required by the libp2p ping, is the peer id of the node. The peer id is not checked if
+// empty.
+func (r *ProberRegistry) Validate(addr MultiAddr, peerID PeerID) error {
+	base, _, err := splitHealthcheckAddr(addr)
+	if err != nil {
+		return err
+	}
+
+	if ip := addrIP(addr); ip != nil && ip.IsUnspecified() {
+		return fmt.Errorf("%w: %s", ErrUnspecifiedAddr, addr)
+	}
+
+	protocol, _, err := r.lookup(addr)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrNoProber, addr)
+	}
+
+	addrPeer, err := base.ValueForProtocol(libp2pMultiaddr.P_P2P)
+	if err != nil {
+		if protocol == PING_HEALTHCHECK || protocol == "p2p" {
+			return fmt.Errorf("%w: %s", ErrMissingPeerID, addr)
+		}
+		return nil
+	}
+	if peerID == "" {
+		return nil
+	}
+
+	// peer ids have several encodings, they are compared decoded
+	expected, err := peerstore.Decode(peerID)
+	if err != nil {
+		return fmt.Errorf("invalid peer id %s: %w", peerID, err)
+	}
+	actual, err := peerstore.Decode(addrPeer)
+	if err != nil {
+		return fmt.Errorf("invalid peer id in multiaddr %s: %w", addr, err)
+	}
+	if actual != expected {
+		return fmt.Errorf("%w: %s is %s, not %s", ErrPeerIDMismatch, addr, actual, expected)
+	}
+	return nil
+}
+
+// CheckReachable probes the multiaddr, returning the reason of the failure if offline
+func (r *ProberRegistry) CheckReachable(ctx context.Context, addr MultiAddr) error {
+	upInfo := r.Probe(ctx, addr)
+	if !upInfo.isOnline {
+		return fmt.Errorf("%w: %s (%s)", ErrUnreachable, addr, upInfo.errReason)
+	}
+	return nil
+}
+
+// PublicAddrs drops the multiaddrs that cannot be dialed from outside the node: the
+// unspecified 0.0.0.0 and :: listen addresses, and the loopback and link-local ones
+func PublicAddrs(addrs []MultiAddr) []MultiAddr {
+	public := make([]MultiAddr, 0, len(addrs))
+	for _, addr := range addrs {
+		ip := addrIP(addr)
+		if ip != nil && (ip.IsUnspecified() || ip.IsLoopback() || ip.IsLinkLocalUnicast()) {
+			log.Debugw("not announcing local multi addr", "addr", addr)
+			continue
+		}
+		public = append(public, addr)
+	}
+	return public
+}
+
+// addrIP returns the ip of the multiaddr, nil if it has none, e.g. a dns address
+func addrIP(addr MultiAddr) net.IP {
+	base, _, err := splitHealthcheckAddr(addr)
+	if err != nil {
+		return nil
+	}
+	for _, code := range []int{libp2pMultiaddr.P_IP4, libp2pMultiaddr.P_IP6} {
+		if ip, err := base.ValueForProtocol(code); err == nil {
+			return net.ParseIP(ip)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,614 @@
+package uptime
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const API_DEFAULT_LIMIT = 100
+const API_MAX_LIMIT = 1000
+const API_MAX_OFFSET = math.MaxInt32 // so that the offset and the end of the page fit an int
+const API_DEFAULT_HISTORY_WINDOW = UPTIME_WINDOW_DAY // history returned when no range is given
+
+const HISTORY_RESOLUTION_RAW = "raw"
+const HISTORY_RESOLUTION_HOURLY = "hourly"
+
+// Codes of the api error responses
+const API_ERR_BAD_REQUEST = "bad_request"
+const API_ERR_NOT_FOUND = "not_found"
+const API_ERR_METHOD_NOT_ALLOWED = "method_not_allowed"
+const API_ERR_STATE_UNAVAILABLE = "state_unavailable"
+const API_ERR_HISTORY_DISABLED = "history_disabled"
+const API_ERR_HOST_UNAVAILABLE = "host_unavailable"
+const API_ERR_INTERNAL = "internal"
+
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// APIError is the body of every non 2xx response of the api
+type APIError struct {
+	Error APIErrorDetail `json:"error"`
+}
+
+type APIErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Page is a paginated list of items
+type Page struct {
+	Items  interface{} `json:"items"`
+	Total  int         `json:"total"`
+	Offset int         `json:"offset"`
+	Limit  int         `json:"limit"`
+}
+
+type MemberResponse struct {
+	ActorID   ActorID     `json:"actorId"`
+	PeerID    PeerID      `json:"peerId"`
+	Creator   ActorID     `json:"creator"`
+	Addresses []MultiAddr `json:"addresses"`
+	// Health is nil until the member has been probed
+	Health *MemberHealthInfo `json:"health"`
+}
+
+type CheckerResponse struct {
+	ActorID   ActorID     `json:"actorId"`
+	PeerID    PeerID      `json:"peerId"`
+	Creator   ActorID     `json:"creator"`
+	Addresses []MultiAddr `json:"addresses"`
+	// Whether the checker is currently reported as offline
+	Reported bool `json:"reported"`
+}
+
+type ReportResponse struct {
+	Checker   ActorID     `json:"checker"`
+	Addresses []MultiAddr `json:"addresses"`
+	// Whether this checker has already voted the reported checker offline
+	Voted bool `json:"voted"`
+	// Epoch the current round of votes started at
+	LastVote ChainEpoch `json:"lastVote"`
+	Voters   []ActorID  `json:"voters"`
+	// Votes evicting the checker, and how many are still missing
+	VotesNeeded    uint64 `json:"votesNeeded"`
+	VotesRemaining uint64 `json:"votesRemaining"`
+}
+
+type VotingResponse struct {
+	TotalCheckers  uint64     `json:"totalCheckers"`
+	VotingDuration ChainEpoch `json:"votingDuration"`
+	// A checker is evicted once its votes exceed the threshold, i.e. with votesNeeded votes
+	VotingThreshold uint64 `json:"votingThreshold"`
+	VotesNeeded     uint64 `json:"votesNeeded"`
+}
+
+// SelfResponse is the identity of the checker, its actor id being the one of its wallet
+type SelfResponse struct {
+	ActorID      ActorID     `json:"actorId"`
+	Wallet       string      `json:"wallet"`
+	PeerID       PeerID      `json:"peerId"`
+	ActorAddress string      `json:"actorAddress"`
+	Addresses    []MultiAddr `json:"addresses"`
+	Registered   bool        `json:"registered"`
+}
+
+// api serves the versioned REST api of the checker
+type api struct {
+	checker *UptimeChecker
+}
+
+// NewAPIHandler returns the handler of the versioned REST api, see openapi.json
+func NewAPIHandler(checker *UptimeChecker) http.Handler {
+	a := &api{checker: checker}
+
+	// multiaddrs are passed url encoded in the path, keep them as a single segment
+	r := mux.NewRouter().UseEncodedPath()
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeAPIError(w, http.StatusNotFound, API_ERR_NOT_FOUND, "no such endpoint")
+	})
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeAPIError(w, http.StatusMethodNotAllowed, API_ERR_METHOD_NOT_ALLOWED, "method not allowed")
+	})
+
+	v1 := r.PathPrefix("/v1").Subrouter()
+	v1.HandleFunc("/openapi.json", a.openAPI).Methods(http.MethodGet)
+	v1.HandleFunc("/members", a.listMembers).Methods(http.MethodGet)
+	v1.HandleFunc("/members/{actorID}", a.getMember).Methods(http.MethodGet)
+	v1.HandleFunc("/members/{actorID}/addresses/{maddr}/history", a.getAddressHistory).Methods(http.MethodGet)
+	v1.HandleFunc("/checkers", a.listCheckers).Methods(http.MethodGet)
+	v1.HandleFunc("/reports", a.listReports).Methods(http.MethodGet)
+	v1.HandleFunc("/voting", a.getVoting).Methods(http.MethodGet)
+	v1.HandleFunc("/self", a.getSelf).Methods(http.MethodGet)
+	v1.HandleFunc("/events", a.streamEvents).Methods(http.MethodGet)
+	v1.HandleFunc("/events/ws", a.streamEventsWebSocket).Methods(http.MethodGet)
+
+	return r
+}
+
+func (a *api) openAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDocument)
+}
+
+func (a *api) listMembers(w http.ResponseWriter, r *http.Request) {
+	offset, limit, ok := parsePagination(w, r)
+	if !ok {
+		return
+	}
+	online, ok := parseOptionalBool(w, r, "online")
+	if !ok {
+		return
+	}
+	creator, ok := parseOptionalActorID(w, r, "creator")
+	if !ok {
+		return
+	}
+
+	state, ok := a.loadState(w, r)
+	if !ok {
+		return
+	}
+
+	ids, err := state.ListMembers()
+	if err != nil {
+		writeStateError(w, err)
+		return
+	}
+	sortActorIDs(ids)
+
+	members := make([]MemberResponse, 0, len(ids))
+	for _, actorID := range ids {
+		member, err := a.member(state, actorID)
+		if err != nil {
+			writeStateError(w, err)
+			return
+		}
+		if member == nil {
+			continue
+		}
+		if creator != nil && member.Creator != *creator {
+			continue
+		}
+		if online != nil && (member.Health != nil && member.Health.IsOnline) != *online {
+			continue
+		}
+		members = append(members, *member)
+	}
+
+	start, end := pageBounds(len(members), offset, limit)
+	writeJSON(w, http.StatusOK, Page{Items: members[start:end], Total: len(members), Offset: offset, Limit: limit})
+}
+
+func (a *api) getMember(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := parseActorIDVar(w, r)
+	if !ok {
+		return
+	}
+
+	state, ok := a.loadState(w, r)
+	if !ok {
+		return
+	}
+
+	member, err := a.member(state, actorID)
+	if err != nil {
+		writeStateError(w, err)
+		return
+	}
+	if member == nil {
+		writeAPIError(w, http.StatusNotFound, API_ERR_NOT_FOUND, fmt.Sprintf("member %d not found", actorID))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, member)
+}
+
+func (a *api) getAddressHistory(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := parseActorIDVar(w, r)
+	if !ok {
+		return
+	}
+
+	addr, err := url.PathUnescape(mux.Vars(r)["maddr"])
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, API_ERR_BAD_REQUEST, fmt.Sprintf("invalid multiaddr: %s", err))
+		return
+	}
+
+	offset, limit, ok := parsePagination(w, r)
+	if !ok {
+		return
+	}
+
+	now := uint64(time.Now().Unix())
+	from, ok := parseUint(w, r, "from", now-uint64(API_DEFAULT_HISTORY_WINDOW/time.Second))
+	if !ok {
+		return
+	}
+	to, ok := parseUint(w, r, "to", now)
+	if !ok {
+		return
+	}
+
+	resolution := r.URL.Query().Get("resolution")
+	if resolution == "" {
+		resolution = HISTORY_RESOLUTION_RAW
+	}
+	if resolution != HISTORY_RESOLUTION_RAW && resolution != HISTORY_RESOLUTION_HOURLY {
+		writeAPIError(w, http.StatusBadRequest, API_ERR_BAD_REQUEST, fmt.Sprintf("unknown resolution %q", resolution))
+		return
+	}
+
+	if a.checker.history == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, API_ERR_HISTORY_DISABLED, "probe history is disabled")
+		return
+	}
+
+	state, ok := a.loadState(w, r)
+	if !ok {
+		return
+	}
+
+	info, err := state.GetMemberInfo(actorID)
+	if err != nil {
+		writeStateError(w, err)
+		return
+	}
+	if info == nil {
+		writeAPIError(w, http.StatusNotFound, API_ERR_NOT_FOUND, fmt.Sprintf("member %d not found", actorID))
+		return
+	}
+	if !containsAddr(info.Addresses, addr) {
+		writeAPIError(w, http.StatusNotFound, API_ERR_NOT_FOUND, fmt.Sprintf("member %d has no address %s", actorID, addr))
+		return
+	}
+
+	if resolution == HISTORY_RESOLUTION_HOURLY {
+		aggregates, err := a.checker.history.Aggregates(r.Context(), actorID, addr, from, to)
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, API_ERR_INTERNAL, err.Error())
+			return
+		}
+		start, end := pageBounds(len(aggregates), offset, limit)
+		writeJSON(w, http.StatusOK, Page{Items: aggregates[start:end], Total: len(aggregates), Offset: offset, Limit: limit})
+		return
+	}
+
+	probes, err := a.checker.history.Probes(r.Context(), actorID, addr, from, to)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, API_ERR_INTERNAL, err.Error())
+		return
+	}
+	start, end := pageBounds(len(probes), offset, limit)
+	writeJSON(w, http.StatusOK, Page{Items: probes[start:end], Total: len(probes), Offset: offset, Limit: limit})
+}
+
+func (a *api) listCheckers(w http.ResponseWriter, r *http.Request) {
+	offset, limit, ok := parsePagination(w, r)
+	if !ok {
+		return
+	}
+	creator, ok := parseOptionalActorID(w, r, "creator")
+	if !ok {
+		return
+	}
+	reported, ok := parseOptionalBool(w, r, "reported")
+	if !ok {
+		return
+	}
+
+	state, ok := a.loadState(w, r)
+	if !ok {
+		return
+	}
+
+	ids, err := state.ListCheckers()
+	if err != nil {
+		writeStateError(w, err)
+		return
+	}
+	sortActorIDs(ids)
+
+	offline, err := state.GetOfflineCheckers()
+	if err != nil {
+		writeStateError(w, err)
+		return
+	}
+	isReported := make(map[ActorID]bool, len(offline))
+	for _, actorID := range offline {
+		isReported[actorID] = true
+	}
+
+	checkers := make([]CheckerResponse, 0, len(ids))
+	for _, actorID := range ids {
+		info, err := state.GetCheckerInfo(actorID)
+		if err != nil {
+			writeStateError(w, err)
+			return
+		}
+		if info == nil {
+			continue
+		}
+		if creator != nil && info.Creator != *creator {
+			continue
+		}
+		if reported != nil && isReported[actorID] != *reported {
+			continue
+		}
+		checkers = append(checkers, CheckerResponse{
+			ActorID:   actorID,
+			PeerID:    info.Id,
+			Creator:   info.Creator,
+			Addresses: nonNilAddrs(info.Addresses),
+			Reported:  isReported[actorID],
+		})
+	}
+
+	start, end := pageBounds(len(checkers), offset, limit)
+	writeJSON(w, http.StatusOK, Page{Items: checkers[start:end], Total: len(checkers), Offset: offset, Limit: limit})
+}
+
+func (a *api) listReports(w http.ResponseWriter, r *http.Request) {
+	offset, limit, ok := parsePagination(w, r)
+	if !ok {
+		return
+	}
+	voted, ok := parseOptionalBool(w, r, "voted")
+	if !ok {
+		return
+	}
+
+	state, ok := a.loadState(w, r)
+	if !ok {
+		return
+	}
+
+	votes, err := state.ListVotes()
+	if err != nil {
+		writeStateError(w, err)
+		return
+	}
+
+	ids := make([]ActorID, 0, len(votes))
+	for actorID := range votes {
+		ids = append(ids, actorID)
+	}
+	sortActorIDs(ids)
+
+	totalCheckers := state.TotalCheckers()
+
+	reports := make([]ReportResponse, 0, len(ids))
+	for _, actorID := range ids {
+		hasVoted, err := state.HasVotedReportedPeer(actorID)
+		if err != nil {
+			writeStateError(w, err)
+			return
+		}
+		if voted != nil && hasVoted != *voted {
+			continue
+		}
+
+		addrs, err := state.ListCheckerMultiAddrs(actorID)
+		if err != nil {
+			writeStateError(w, err)
+			return
+		}
+
+		v := votes[actorID]
+		report := ReportResponse{
+			Checker:        actorID,
+			Addresses:      make([]MultiAddr, 0),
+			Voted:          hasVoted,
+			LastVote:       v.LastVote,
+			Voters:         append([]ActorID{}, v.Votes...),
+			VotesNeeded:    VotesNeeded(totalCheckers),
+			VotesRemaining: v.VotesRemaining(totalCheckers),
+		}
+		if addrs != nil {
+			report.Addresses = nonNilAddrs(*addrs)
+		}
+		reports = append(reports, report)
+	}
+
+	start, end := pageBounds(len(reports), offset, limit)
+	writeJSON(w, http.StatusOK, Page{Items: reports[start:end], Total: len(reports), Offset: offset, Limit: limit})
+}
+
+func (a *api) getVoting(w http.ResponseWriter, r *http.Request) {
+	state, ok := a.loadState(w, r)
+	if !ok {
+		return
+	}
+
+	totalCheckers := state.TotalCheckers()
+	writeJSON(w, http.StatusOK, VotingResponse{
+		TotalCheckers:   totalCheckers,
+		VotingDuration:  state.VotingDuration(),
+		VotingThreshold: VotingThreshold(totalCheckers),
+		VotesNeeded:     VotesNeeded(totalCheckers),
+	})
+}
+
+func (a *api) getSelf(w http.ResponseWriter, r *http.Request) {
+	// e.g. a checker simulated against a memory state
+	if a.checker.node == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, API_ERR_HOST_UNAVAILABLE, "checker has no libp2p host")
+		return
+	}
+
+	state, ok := a.loadState(w, r)
+	if !ok {
+		return
+	}
+
+	registered, err := state.HasRegistered(a.checker.self)
+	if err != nil {
+		writeStateError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, SelfResponse{
+		ActorID:      a.checker.self,
+		Wallet:       a.checker.wallet.String(),
+		PeerID:       a.checker.node.ID().String(),
+		ActorAddress: a.checker.uptimeCheckerAddress.String(),
+		Addresses:    nonNilAddrs(a.checker.checkerAddresses),
+		Registered:   registered,
+	})
+}
+
+// member returns the member info merged with its health info, nil if not a member
+func (a *api) member(state *CacheState, actorID ActorID) (*MemberResponse, error) {
+	info, err := state.GetMemberInfo(actorID)
+	if err != nil || info == nil {
+		return nil, err
+	}
+
+	member := MemberResponse{
+		ActorID:   actorID,
+		PeerID:    info.Id,
+		Creator:   info.Creator,
+		Addresses: nonNilAddrs(info.Addresses),
+	}
+	if health, ok := a.checker.health.Member(actorID); ok {
+		member.Health = &health
+	}
+	return &member, nil
+}
+
+func (a *api) loadState(w http.ResponseWriter, r *http.Request) (*CacheState, bool) {
+	state, err := a.checker.currentState(r.Context())
+	if err != nil {
+		writeStateError(w, err)
+		return nil, false
+	}
+	return state, true
+}
+
+func writeStateError(w http.ResponseWriter, err error) {
+	log.Errorw("cannot read actor state", "err", err)
+	writeAPIError(w, http.StatusServiceUnavailable, API_ERR_STATE_UNAVAILABLE, err.Error())
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code string, message string) {
+	writeJSON(w, status, APIError{Error: APIErrorDetail{Code: code, Message: message}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		log.Errorw("cannot write api response", "err", err)
+	}
+}
+
+func parseActorIDVar(w http.ResponseWriter, r *http.Request) (ActorID, bool) {
+	actorID, err := strconv.ParseUint(mux.Vars(r)["actorID"], 10, 64)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, API_ERR_BAD_REQUEST, fmt.Sprintf("invalid actor id: %s", err))
+		return 0, false
+	}
+	return actorID, true
+}
+
+// parsePagination reads the offset and limit query params, limit is capped to API_MAX_LIMIT
+// and offset to API_MAX_OFFSET
+func parsePagination(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	offset, ok := parseUint(w, r, "offset", 0)
+	if !ok {
+		return 0, 0, false
+	}
+	if offset > API_MAX_OFFSET {
+		writeAPIError(w, http.StatusBadRequest, API_ERR_BAD_REQUEST, fmt.Sprintf("offset must be within [0, %d]", API_MAX_OFFSET))
+		return 0, 0, false
+	}
+	limit, ok := parseUint(w, r, "limit", API_DEFAULT_LIMIT)
+	if !ok {
+		return 0, 0, false
+	}
+	if limit == 0 || limit > API_MAX_LIMIT {
+		writeAPIError(w, http.StatusBadRequest, API_ERR_BAD_REQUEST, fmt.Sprintf("limit must be within [1, %d]", API_MAX_LIMIT))
+		return 0, 0, false
+	}
+	return int(offset), int(limit), true
+}
+
+func parseUint(w http.ResponseWriter, r *http.Request, name string, def uint64) (uint64, bool) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, true
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, API_ERR_BAD_REQUEST, fmt.Sprintf("invalid %s: %s", name, err))
+		return 0, false
+	}
+	return v, true
+}
+
+// parseOptionalBool returns nil if the query param is not set
+func parseOptionalBool(w http.ResponseWriter, r *http.Request, name string) (*bool, bool) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return nil, true
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, API_ERR_BAD_REQUEST, fmt.Sprintf("invalid %s: %s", name, err))
+		return nil, false
+	}
+	return &v, true
+}
+
+// parseOptionalActorID returns nil if the query param is not set
+func parseOptionalActorID(w http.ResponseWriter, r *http.Request, name string) (*ActorID, bool) {
+	if r.URL.Query().Get(name) == "" {
+		return nil, true
+	}
+	v, ok := parseUint(w, r, name, 0)
+	if !ok {
+		return nil, false
+	}
+	return &v, true
+}
+
+func pageBounds(total int, offset int, limit int) (int, int) {
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return offset, end
+}
+
+func sortActorIDs(ids []ActorID) {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+}
+
+func containsAddr(addrs []MultiAddr, addr MultiAddr) bool {
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// nonNilAddrs makes sure empty address lists are encoded as [] instead of null
+func nonNilAddrs(addrs []MultiAddr) []MultiAddr {
+	if addrs == nil {
+		return make([]MultiAddr, 0)
+	}
+	return addrs
+}
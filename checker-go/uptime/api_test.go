@@ -0,0 +1,226 @@
+package uptime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	chainTypes "github.com/filecoin-project/lotus/chain/types"
+)
+
+func TestAPIPaginationBounds(t *testing.T) {
+	u := newTestChecker(t, NewMemoryStateSource(newTestState()))
+	if err := u.watcher.refreshLatest(context.Background(), chainTypes.EmptyTSK); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(NewAPIHandler(u))
+	defer server.Close()
+
+	cases := []struct {
+		query  string
+		status int
+	}{
+		{"offset=0&limit=1", http.StatusOK},
+		{"offset=2147483647&limit=1000", http.StatusOK},
+		{"offset=2147483648", http.StatusBadRequest},
+		{"offset=9223372036854775808", http.StatusBadRequest},
+		{"offset=18446744073709551615", http.StatusBadRequest},
+		{"offset=18446744073709551616", http.StatusBadRequest},
+		{"limit=0", http.StatusBadRequest},
+		{"limit=1001", http.StatusBadRequest},
+	}
+
+	for _, path := range []string{"/v1/members", "/v1/checkers", "/v1/reports"} {
+		for _, c := range cases {
+			resp, err := http.Get(server.URL + path + "?" + c.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != c.status {
+				t.Errorf("%s?%s: status %d, want %d", path, c.query, resp.StatusCode, c.status)
+			}
+		}
+	}
+}
+
+// validateSchema checks that the decoded json value matches the schema of the openapi
+// document: the declared types, the required properties and no undeclared one
+func validateSchema(t *testing.T, doc map[string]interface{}, schema map[string]interface{}, value interface{}, path string) {
+	t.Helper()
+
+	schema = resolveSchema(t, doc, schema)
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); !nullable {
+			t.Errorf("%s: null is not nullable", path)
+		}
+		return
+	}
+
+	typ, _ := schema["type"].(string)
+	switch typ {
+	case "object", "":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			t.Errorf("%s: %T is not an object", path, value)
+			return
+		}
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, ok := obj[name.(string)]; !ok {
+					t.Errorf("%s: missing required %s", path, name)
+				}
+			}
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		additional, _ := schema["additionalProperties"].(map[string]interface{})
+		for name, v := range obj {
+			if prop, ok := props[name].(map[string]interface{}); ok {
+				validateSchema(t, doc, prop, v, path+"."+name)
+			} else if additional != nil {
+				validateSchema(t, doc, additional, v, path+"."+name)
+			} else {
+				t.Errorf("%s: undeclared property %s", path, name)
+			}
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			t.Errorf("%s: %T is not an array", path, value)
+			return
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, v := range arr {
+			validateSchema(t, doc, items, v, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			t.Errorf("%s: %T is not a string", path, value)
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != math.Trunc(n) {
+			t.Errorf("%s: %v is not an integer", path, value)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			t.Errorf("%s: %T is not a number", path, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			t.Errorf("%s: %T is not a boolean", path, value)
+		}
+	}
+}
+
+// resolveSchema follows the $ref of the schema and merges the schemas of its allOf, the
+// later properties overriding the former ones
+func resolveSchema(t *testing.T, doc map[string]interface{}, schema map[string]interface{}) map[string]interface{} {
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		merged := map[string]interface{}{}
+		props := map[string]interface{}{}
+		required := []interface{}{}
+		for _, s := range allOf {
+			resolved := resolveSchema(t, doc, s.(map[string]interface{}))
+			for k, v := range resolved {
+				merged[k] = v
+			}
+			if p, ok := resolved["properties"].(map[string]interface{}); ok {
+				for k, v := range p {
+					props[k] = v
+				}
+			}
+			if r, ok := resolved["required"].([]interface{}); ok {
+				required = append(required, r...)
+			}
+		}
+		merged["properties"] = props
+		merged["required"] = required
+		if nullable, ok := schema["nullable"]; ok {
+			merged["nullable"] = nullable
+		}
+		return merged
+	}
+
+	ref, ok := schema["$ref"].(string)
+	if !ok {
+		return schema
+	}
+	var node interface{} = doc
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		node = node.(map[string]interface{})[part]
+	}
+	resolved, ok := node.(map[string]interface{})
+	if !ok {
+		t.Fatalf("cannot resolve %s", ref)
+	}
+	return resolveSchema(t, doc, resolved)
+}
+
+func responseSchema(t *testing.T, doc map[string]interface{}, path string, status int) map[string]interface{} {
+	node := doc["paths"].(map[string]interface{})[path].(map[string]interface{})["get"].(map[string]interface{})
+	response, ok := node["responses"].(map[string]interface{})[strconv.Itoa(status)].(map[string]interface{})
+	if !ok {
+		t.Fatalf("no %d response documented for %s", status, path)
+	}
+	content := response["content"].(map[string]interface{})["application/json"].(map[string]interface{})
+	return content["schema"].(map[string]interface{})
+}
+
+func TestAPIResponsesMatchSchema(t *testing.T) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(openAPIDocument, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	u := newTestChecker(t, NewMemoryStateSource(newTestState()))
+	ctx := context.Background()
+	if err := u.watcher.refreshLatest(ctx, chainTypes.EmptyTSK); err != nil {
+		t.Fatal(err)
+	}
+	addrs := []MultiAddr{testOnlineAddr, testOfflineAddr}
+	infos := u.multiAddrsUp(ctx, &addrs)
+	if err := u.recordMemberHealthInfo(ctx, testMember, &infos); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(NewAPIHandler(u))
+	defer server.Close()
+
+	cases := []struct {
+		url    string
+		path   string
+		status int
+	}{
+		{"/v1/members", "/v1/members", http.StatusOK},
+		{"/v1/members/200", "/v1/members/{actorID}", http.StatusOK},
+		{"/v1/checkers", "/v1/checkers", http.StatusOK},
+		{"/v1/voting", "/v1/voting", http.StatusOK},
+		// the test checker has no libp2p host
+		{"/v1/self", "/v1/self", http.StatusServiceUnavailable},
+	}
+
+	for _, c := range cases {
+		resp, err := http.Get(server.URL + c.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body interface{}
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != c.status {
+			t.Errorf("%s: status %d, want %d", c.url, resp.StatusCode, c.status)
+			continue
+		}
+		validateSchema(t, doc, responseSchema(t, doc, c.path, c.status), body, c.url)
+	}
+}
@@ -0,0 +1,106 @@
+package uptime
+
+import (
+	"sort"
+	"time"
+)
+
+const AVAILABILITY_BUCKET = 5 * time.Minute // granularity of the uptime windows
+
+const UPTIME_WINDOW_HOUR = time.Hour
+const UPTIME_WINDOW_DAY = 24 * time.Hour
+const UPTIME_WINDOW_WEEK = 7 * UPTIME_WINDOW_DAY
+const UPTIME_WINDOW_MONTH = 30 * UPTIME_WINDOW_DAY
+
+// UptimeInfo is the percentage of online probes over the rolling windows
+type UptimeInfo struct {
+	LastHour  float64 `json:"lastHour"`
+	LastDay   float64 `json:"lastDay"`
+	LastWeek  float64 `json:"lastWeek"`
+	LastMonth float64 `json:"lastMonth"`
+}
+
+type availabilityBucket struct {
+	start  int64
+	checks uint64
+	online uint64
+}
+
+// availabilityTracker counts the probes per time bucket, up to the longest uptime window
+type availabilityTracker struct {
+	buckets []availabilityBucket
+}
+
+func newAvailabilityTracker() *availabilityTracker {
+	return &availabilityTracker{
+		buckets: make([]availabilityBucket, 0),
+	}
+}
+
+// add records a probe checked at the unix timestamp
+func (a *availabilityTracker) add(checkedTime uint64, isOnline bool) {
+	a.addCounts(checkedTime, 1, boolToCount(isOnline))
+}
+
+func (a *availabilityTracker) addCounts(checkedTime uint64, checks uint64, online uint64) {
+	bucketSecs := int64(AVAILABILITY_BUCKET / time.Second)
+	start := int64(checkedTime) / bucketSecs * bucketSecs
+
+	// buckets are sorted by start, probes usually land in the last one
+	i := sort.Search(len(a.buckets), func(i int) bool { return a.buckets[i].start >= start })
+	if i < len(a.buckets) && a.buckets[i].start == start {
+		a.buckets[i].checks += checks
+		a.buckets[i].online += online
+	} else {
+		a.buckets = append(a.buckets, availabilityBucket{})
+		copy(a.buckets[i+1:], a.buckets[i:])
+		a.buckets[i] = availabilityBucket{start: start, checks: checks, online: online}
+	}
+
+	a.prune(int64(checkedTime))
+}
+
+// prune drops the buckets older than the longest uptime window
+func (a *availabilityTracker) prune(now int64) {
+	cutoff := now - int64(UPTIME_WINDOW_MONTH/time.Second)
+
+	i := 0
+	for i < len(a.buckets) && a.buckets[i].start < cutoff {
+		i++
+	}
+	if i > 0 {
+		a.buckets = append(a.buckets[:0], a.buckets[i:]...)
+	}
+}
+
+// uptime returns the percentage of online probes within the window ending at now
+func (a *availabilityTracker) uptime(now int64, window time.Duration) float64 {
+	from := now - int64(window/time.Second)
+
+	var checks, online uint64
+	for i := len(a.buckets) - 1; i >= 0 && a.buckets[i].start >= from; i-- {
+		checks += a.buckets[i].checks
+		online += a.buckets[i].online
+	}
+
+	if checks == 0 {
+		return 0
+	}
+	return float64(online) * 100 / float64(checks)
+}
+
+func (a *availabilityTracker) uptimeInfo(now int64) UptimeInfo {
+	return UptimeInfo{
+		LastHour:  a.uptime(now, UPTIME_WINDOW_HOUR),
+		LastDay:   a.uptime(now, UPTIME_WINDOW_DAY),
+		LastWeek:  a.uptime(now, UPTIME_WINDOW_WEEK),
+		LastMonth: a.uptime(now, UPTIME_WINDOW_MONTH),
+	}
+}
+
+func boolToCount(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
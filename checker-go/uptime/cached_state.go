@@ -1,38 +1,69 @@
 package uptime
 
 import (
-	"sync"
 	"context"
 
 	"github.com/filecoin-project/lotus/api/v0api"
+	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/go-address"
 )
 
-type InnerState = HAMTState
-
 type CacheState struct {
 	self ActorID
 
-	inner InnerState
+	inner StateReader
 	
-	// Local cache of voted checkers. TODO: use LRU cache
-	processedCheckers map[ActorID]bool
-
-	rwLock sync.RWMutex
+	// Local cache of the checkers already voted for, shared with the next states
+	votes *voteCache
 }
 
 func Load(ctx context.Context, api v0api.FullNode, actorAddr address.Address, self ActorID) (CacheState, error)  {
-	s, err := LoadHAMTState(ctx, api, actorAddr)
+	return LoadAt(ctx, api, actorAddr, self, types.EmptyTSK)
+}
+
+// LoadAt loads the state of the actor at the tipset
+func LoadAt(ctx context.Context, api v0api.FullNode, actorAddr address.Address, self ActorID, tsk types.TipSetKey) (CacheState, error)  {
+	s, err := LoadHAMTStateAt(ctx, api, actorAddr, tsk)
 	if err != nil {
+		stateLoadFailures.Inc()
 		return CacheState{}, err
 	}
 	return CacheState {
 		self: self,
-		inner: s,
-		processedCheckers: make(map[ActorID]bool),
+		inner: &s,
+		votes: newVoteCache(DEFAULT_VOTE_CACHE_SIZE, DEFAULT_VOTE_CACHE_TTL),
 	}, nil
 }
 
+// NewCacheState wraps the state read by the reader, self being the actor id of this checker
+func NewCacheState(self ActorID, inner StateReader) *CacheState {
+	return newCacheStateWithVotes(self, inner, newVoteCache(DEFAULT_VOTE_CACHE_SIZE, DEFAULT_VOTE_CACHE_TTL))
+}
+
+func newCacheStateWithVotes(self ActorID, inner StateReader, votes *voteCache) *CacheState {
+	return &CacheState {
+		self: self,
+		inner: inner,
+		votes: votes,
+	}
+}
+
+// Next returns the state read by the reader, keeping the votes known by this state that
+// its changes do not void. Use it to follow the actor across tipsets.
+func (c *CacheState) Next(inner StateReader) *CacheState {
+	next := newCacheStateWithVotes(c.self, inner, c.votes)
+
+	d, err := next.Diff(c)
+	if err != nil {
+		log.Warnw("cannot diff states, dropping the known votes", "err", err)
+		c.votes.purge()
+		return next
+	}
+	c.votes.invalidate(d)
+
+	return next
+}
+
 func (c *CacheState) HasRegistered(actor ActorID) (bool, error) {
 	return c.inner.HasRegistered(actor)
 }
@@ -84,6 +115,39 @@ func (c *CacheState) ListCheckerMultiAddrs(actorID ActorID) (*[]MultiAddr, error
 	return c.inner.ListCheckerMultiAddrs(actorID)
 }
 
+func (c *CacheState) GetMemberInfo(actorID ActorID) (*NodeInfo, error) {
+	return c.inner.GetMemberInfo(actorID)
+}
+
+func (c *CacheState) GetCheckerInfo(actorID ActorID) (*NodeInfo, error) {
+	return c.inner.GetCheckerInfo(actorID)
+}
+
+func (c *CacheState) GetOfflineCheckers() ([]ActorID, error) {
+	return c.inner.GetOfflineCheckers()
+}
+
+func (c *CacheState) GetVotes(reported ActorID) (*Votes, error) {
+	return c.inner.GetVotes(reported)
+}
+
+func (c *CacheState) ListVotes() (map[ActorID]*Votes, error) {
+	return c.inner.ListVotes()
+}
+
+func (c *CacheState) TotalCheckers() uint64 {
+	return c.inner.TotalCheckers()
+}
+
+func (c *CacheState) VotingDuration() ChainEpoch {
+	return c.inner.VotingDuration()
+}
+
+// Diff returns the changes of the actor state since prev
+func (c *CacheState) Diff(prev *CacheState) (*StateDiff, error) {
+	return c.inner.Diff(prev.inner)
+}
+
 func (c *CacheState) HasVotedReportedPeer(targetPeer ActorID) (bool, error) {
 	if c.hasVotedReportedPeerLocally(targetPeer) {
 		return true, nil
@@ -92,10 +156,12 @@ func (c *CacheState) HasVotedReportedPeer(targetPeer ActorID) (bool, error) {
 }
 
 func (c *CacheState) hasVotedReportedPeerLocally(targetPeer ActorID) (bool) {
-	c.rwLock.RLock()
-	defer c.rwLock.RUnlock()
-	_, ok := c.processedCheckers[targetPeer];
-	return ok
+	return c.votes.has(targetPeer)
+}
+
+// recordVoted remembers that this checker voted for the reported peer
+func (c *CacheState) recordVoted(targetPeer ActorID) {
+	c.votes.add(targetPeer)
 }
 
 func (c *CacheState) hasVotedReportedPeerInActor(reported ActorID) (bool, error) {
@@ -163,6 +163,15 @@ func (t *NodeInfo) UnmarshalCBOR(r io.Reader) (err error) {
 		}
 
 		switch name {
+		case "id":
+			{
+				sval, err := cbg.ReadString(cr)
+				if err != nil {
+					return err
+				}
+
+				t.Id = string(sval)
+			}
 		case "creator":
 			{
 
@@ -205,105 +214,3 @@ func (t *NodeInfo) UnmarshalCBOR(r io.Reader) (err error) {
 
 	return nil
 }
-
-func (t *HAMTStateInner) UnmarshalCBOR(r io.Reader) (err error) {
-	*t = HAMTStateInner{}
-
-	cr := cbg.NewCborReader(r)
-
-	maj, extra, err := cr.ReadHeader()
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if err == io.EOF {
-			err = io.ErrUnexpectedEOF
-		}
-	}()
-
-	if maj != cbg.MajMap {
-		return fmt.Errorf("cbor input should be of type map")
-	}
-
-	if extra > cbg.MaxLength {
-		return fmt.Errorf("HAMTStateInner: map struct too large (%d)", extra)
-	}
-
-	var name string
-	n := extra
-
-	for i := uint64(0); i < n; i++ {
-
-		{
-			sval, err := cbg.ReadString(cr)
-			if err != nil {
-				return err
-			}
-
-			name = string(sval)
-		}
-
-		switch name {
-		// t.Members (cid.Cid) (struct)
-		case "members":
-
-			{
-
-				c, err := cbg.ReadCid(cr)
-				if err != nil {
-					return xerrors.Errorf("failed to read cid field t.Members: %w", err)
-				}
-
-				t.Members = c
-
-			}
-			// t.Checkers (cid.Cid) (struct)
-		case "checkers":
-
-			{
-
-				c, err := cbg.ReadCid(cr)
-				if err != nil {
-					return xerrors.Errorf("failed to read cid field t.Checkers: %w", err)
-				}
-
-				t.Checkers = c
-
-			}
-			// t.OfflineCheckers (cid.Cid) (struct)
-		case "offline_checkers":
-
-			{
-
-				c, err := cbg.ReadCid(cr)
-				if err != nil {
-					return xerrors.Errorf("failed to read cid field t.Offline_checkers: %w", err)
-				}
-
-				t.OfflineCheckers = c
-
-			}
-			// t.TotalCheckers (uint64) (uint64)
-		case "total_checkers":
-
-			{
-
-				maj, extra, err = cr.ReadHeader()
-				if err != nil {
-					return err
-				}
-				if maj != cbg.MajUnsignedInt {
-					return fmt.Errorf("wrong type for uint64 field")
-				}
-				t.TotalCheckers = uint64(extra)
-
-			}
-
-		default:
-			// Field doesn't exist on this type, so ignore it
-			cbg.ScanForLinks(r, func(cid.Cid) {})
-		}
-	}
-
-	return nil
-}
@@ -0,0 +1,379 @@
+package uptime
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	lapi "github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/v0api"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+)
+
+const WATCHER_RETRY_INTERVAL = 5 * time.Second // delay before resubscribing to the chain head changes
+const DEFAULT_CONFIDENCE = 5                   // epochs behind the head of the confirmed state, as the lotus message confidence
+
+// Types of the head changes notified by ChainNotify
+const HEAD_CHANGE_CURRENT = "current"
+const HEAD_CHANGE_APPLY = "apply"
+const HEAD_CHANGE_REVERT = "revert"
+
+var ErrNoChain = errors.New("no chain to follow, set a lotus node or a state source following a chain")
+
+// ChainWatcher follows the chain head and reloads the state of the actor only when its head
+// cid changes. The monitors share the latest state instead of each polling the node.
+//
+// It also keeps the confirmed state, pinned to the tipset confidence epochs behind the head,
+// which decisions that should not be undone by a short reorg are based on, and notifies
+// the reorgs so that such decisions can be re-evaluated.
+type ChainWatcher struct {
+	chain      ChainSource // notifies the head changes, the lotus node by default
+	actor      address.Address
+	source     StateSource // loads the states of the actor, from the chain by default
+	self       ActorID
+	confidence abi.ChainEpoch
+
+	head  cid.Cid     // head cid of the actor in the latest state
+	state *CacheState // the latest state, nil until first loaded
+	votes *voteCache  // the votes of this checker, shared by all the states
+	ready chan struct{}
+
+	confirmedHead cid.Cid
+	confirmed     *CacheState
+	confirmedTs   *types.TipSet
+
+	subscribers    map[uint64]chan struct{}
+	reorgs         map[uint64]chan abi.ChainEpoch
+	nextSubscriber uint64
+
+	rwLock sync.RWMutex
+}
+
+func NewChainWatcher(api v0api.FullNode, actor address.Address, self ActorID) *ChainWatcher {
+	var chain ChainSource
+	if api != nil {
+		chain = api
+	}
+	return &ChainWatcher{
+		chain:       chain,
+		actor:       actor,
+		source:      NewChainStateSource(api, actor),
+		self:        self,
+		confidence:  DEFAULT_CONFIDENCE,
+		votes:       newVoteCache(DEFAULT_VOTE_CACHE_SIZE, DEFAULT_VOTE_CACHE_TTL),
+		ready:       make(chan struct{}),
+		subscribers: make(map[uint64]chan struct{}),
+		reorgs:      make(map[uint64]chan abi.ChainEpoch),
+	}
+}
+
+// SetStateSource sets where the states of the actor are loaded from, e.g. a
+// MemoryStateSource to run without a lotus node. A source that is also a ChainSource
+// notifies the head changes instead of the node.
+func (w *ChainWatcher) SetStateSource(source StateSource) {
+	w.rwLock.Lock()
+	defer w.rwLock.Unlock()
+	w.source = source
+	if chain, ok := source.(ChainSource); ok {
+		w.chain = chain
+	}
+}
+
+// Chain returns the chain the watcher follows
+func (w *ChainWatcher) Chain() ChainSource {
+	w.rwLock.RLock()
+	defer w.rwLock.RUnlock()
+	return w.chain
+}
+
+// SetConfidence sets the number of epochs the confirmed state is behind the head
+func (w *ChainWatcher) SetConfidence(confidence abi.ChainEpoch) {
+	w.rwLock.Lock()
+	defer w.rwLock.Unlock()
+	w.confidence = confidence
+}
+
+// Confidence returns the number of epochs the confirmed state is behind the head
+func (w *ChainWatcher) Confidence() abi.ChainEpoch {
+	w.rwLock.RLock()
+	defer w.rwLock.RUnlock()
+	return w.confidence
+}
+
+// Run follows the chain head changes until the context is done, resubscribing if the
+// notifications stop, e.g. when the connection to the node is lost
+func (w *ChainWatcher) Run(ctx context.Context) {
+	for {
+		if err := w.watch(ctx); err != nil {
+			log.Errorw("cannot watch chain head changes", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(WATCHER_RETRY_INTERVAL):
+		}
+	}
+}
+
+func (w *ChainWatcher) watch(ctx context.Context) error {
+	chain := w.Chain()
+	if chain == nil {
+		return ErrNoChain
+	}
+
+	notifs, err := chain.ChainNotify(ctx)
+	if err != nil {
+		return err
+	}
+
+	for changes := range notifs {
+		reverted, hasReverted := lowestReverted(changes)
+
+		ts := latestApplied(changes)
+		if ts == nil {
+			continue
+		}
+
+		if err := w.refresh(ctx, ts); err != nil {
+			stateLoadFailures.Inc()
+			log.Errorw("cannot refresh actor state", "height", ts.Height(), "err", err)
+		}
+
+		// notified once the states are reloaded, so that decisions are re-evaluated on the new chain
+		if hasReverted {
+			log.Warnw("chain reorg", "reverted", reverted, "head", ts.Height())
+			w.publishReorg(reverted)
+		}
+	}
+
+	log.Warnw("chain head notifications closed")
+	return nil
+}
+
+// refresh reloads the latest and confirmed states if the head of the actor changed
+func (w *ChainWatcher) refresh(ctx context.Context, ts *types.TipSet) error {
+	if err := w.refreshLatest(ctx, ts.Key()); err != nil {
+		return err
+	}
+	return w.refreshConfirmed(ctx, ts)
+}
+
+func (w *ChainWatcher) refreshLatest(ctx context.Context, tsk types.TipSetKey) error {
+	w.rwLock.RLock()
+	source := w.source
+	w.rwLock.RUnlock()
+
+	head, err := source.Head(ctx, tsk)
+	if err != nil {
+		return err
+	}
+
+	w.rwLock.RLock()
+	prev := w.state
+	unchanged := prev != nil && w.head == head
+	w.rwLock.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	s, err := source.Load(ctx, head)
+	if err != nil {
+		return err
+	}
+
+	log.Debugw("actor state changed", "head", head)
+
+	// the votes voided by the changes since the previous state are dropped
+	state := w.newState(s)
+	if prev != nil {
+		state = prev.Next(s)
+	}
+
+	w.rwLock.Lock()
+	defer w.rwLock.Unlock()
+
+	first := w.state == nil
+	w.head = head
+	w.state = state
+	if first {
+		close(w.ready)
+	}
+
+	for _, ch := range w.subscribers {
+		// notifications are coalesced, subscribers only need to know the state changed
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+func (w *ChainWatcher) refreshConfirmed(ctx context.Context, head *types.TipSet) error {
+	w.rwLock.RLock()
+	height := head.Height() - w.confidence
+	source := w.source
+	chain := w.chain
+	w.rwLock.RUnlock()
+	if height < 0 {
+		height = 0
+	}
+
+	ts, err := chain.ChainGetTipSetByHeight(ctx, height, head.Key())
+	if err != nil {
+		return err
+	}
+
+	actorHead, err := source.Head(ctx, ts.Key())
+	if err != nil {
+		return err
+	}
+
+	w.rwLock.RLock()
+	unchanged := w.confirmed != nil && w.confirmedHead == actorHead
+	w.rwLock.RUnlock()
+
+	var confirmed *CacheState
+	if !unchanged {
+		s, err := source.Load(ctx, actorHead)
+		if err != nil {
+			return err
+		}
+		confirmed = w.newState(s)
+	}
+
+	w.rwLock.Lock()
+	defer w.rwLock.Unlock()
+
+	w.confirmedTs = ts
+	if confirmed != nil {
+		w.confirmedHead = actorHead
+		w.confirmed = confirmed
+	}
+
+	return nil
+}
+
+// setSelf sets the checker the states are read for, before the watcher is run
+func (w *ChainWatcher) setSelf(self ActorID) {
+	w.rwLock.Lock()
+	defer w.rwLock.Unlock()
+	w.self = self
+}
+
+// newState wraps the state read by the reader, sharing the votes known by the watcher
+func (w *ChainWatcher) newState(inner StateReader) *CacheState {
+	return newCacheStateWithVotes(w.self, inner, w.votes)
+}
+
+// State returns the latest state of the actor, nil if not loaded yet
+func (w *ChainWatcher) State() *CacheState {
+	w.rwLock.RLock()
+	defer w.rwLock.RUnlock()
+	return w.state
+}
+
+// Confirmed returns the state of the actor confidence epochs behind the head and the tipset
+// it was loaded at, nil if not loaded yet
+func (w *ChainWatcher) Confirmed() (*CacheState, *types.TipSet) {
+	w.rwLock.RLock()
+	defer w.rwLock.RUnlock()
+	return w.confirmed, w.confirmedTs
+}
+
+// Wait blocks until the state of the actor is first loaded
+func (w *ChainWatcher) Wait(ctx context.Context) (*CacheState, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-w.ready:
+		return w.State(), nil
+	}
+}
+
+// Subscribe returns a channel notified when the state of the actor changes and the function
+// to cancel the subscription
+func (w *ChainWatcher) Subscribe() (<-chan struct{}, func()) {
+	w.rwLock.Lock()
+	defer w.rwLock.Unlock()
+
+	id := w.nextSubscriber
+	w.nextSubscriber++
+
+	ch := make(chan struct{}, 1)
+	w.subscribers[id] = ch
+
+	cancel := func() {
+		w.rwLock.Lock()
+		defer w.rwLock.Unlock()
+		delete(w.subscribers, id)
+	}
+	return ch, cancel
+}
+
+// SubscribeReorgs returns a channel receiving the lowest reverted height of every reorg and
+// the function to cancel the subscription. The states are already reloaded on the new chain
+// when a reorg is received.
+func (w *ChainWatcher) SubscribeReorgs(buffer int) (<-chan abi.ChainEpoch, func()) {
+	w.rwLock.Lock()
+	defer w.rwLock.Unlock()
+
+	id := w.nextSubscriber
+	w.nextSubscriber++
+
+	ch := make(chan abi.ChainEpoch, buffer)
+	w.reorgs[id] = ch
+
+	cancel := func() {
+		w.rwLock.Lock()
+		defer w.rwLock.Unlock()
+		delete(w.reorgs, id)
+	}
+	return ch, cancel
+}
+
+func (w *ChainWatcher) publishReorg(reverted abi.ChainEpoch) {
+	w.rwLock.RLock()
+	defer w.rwLock.RUnlock()
+
+	for id, ch := range w.reorgs {
+		select {
+		case ch <- reverted:
+		default:
+			log.Warnw("reorg subscriber is lagging behind, dropping reorg", "subscriber", id, "reverted", reverted)
+		}
+	}
+}
+
+// latestApplied returns the last tipset applied by the head changes, nil if they only revert
+func latestApplied(changes []*lapi.HeadChange) *types.TipSet {
+	for i := len(changes) - 1; i >= 0; i-- {
+		switch changes[i].Type {
+		case HEAD_CHANGE_CURRENT, HEAD_CHANGE_APPLY:
+			return changes[i].Val
+		}
+	}
+	return nil
+}
+
+// lowestReverted returns the height of the lowest tipset reverted by the head changes
+func lowestReverted(changes []*lapi.HeadChange) (abi.ChainEpoch, bool) {
+	found := false
+	var lowest abi.ChainEpoch
+	for _, change := range changes {
+		if change.Type != HEAD_CHANGE_REVERT || change.Val == nil {
+			continue
+		}
+		if !found || change.Val.Height() < lowest {
+			lowest = change.Val.Height()
+			found = true
+		}
+	}
+	return lowest, found
+}
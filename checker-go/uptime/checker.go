@@ -2,8 +2,9 @@ package uptime
 
 import (
 	"context"
-	"sync"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	logging "github.com/ipfs/go-log/v2"
@@ -12,16 +13,14 @@ import (
 	"github.com/filecoin-project/go-address"
 	chainTypes "github.com/filecoin-project/lotus/chain/types"
 	"github.com/filecoin-project/go-state-types/abi"
-	"github.com/filecoin-project/go-state-types/big"
 
 	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
 	"github.com/libp2p/go-libp2p-core/host"
-	libp2pMultiaddr "github.com/multiformats/go-multiaddr"
-	peerstore "github.com/libp2p/go-libp2p-core/peer"
 )
 
 var log = logging.Logger("uptime")
 
+const INIT_METHOD = 1
 const NEW_CHECKER_METHOD = 2
 const NEW_MEMBER_METHOD = 3
 const EDIT_CHECKER_METHOD = 4
@@ -33,23 +32,47 @@ const REPORT_CHECKER_METHOD = 8
 const PING_TIMEOUT = 120 * time.Second // 120 seconds
 const DEFAULT_SLEEP_SECONDS = 5 * time.Second // 5 seconds
 
+var ErrNoNode = errors.New("no lotus node to send the messages to the actor")
+
 // UptimeChecker maintains the uptime of member nodes
 type UptimeChecker struct {
 	api v0api.FullNode
 
-	self ActorID
+	self ActorID // the actor id of the wallet, resolved on Start if not set
 	walletIndex int
+	wallet address.Address // the wallet signing the messages, the one at walletIndex if not set
 	uptimeCheckerAddress address.Address
 	
-	checkerAddresses []MultiAddr
-	nodeAddresses map[ActorID]map[MultiAddr]HealtcheckInfo
+	checkerAddresses []MultiAddr // the addresses announced when registering, reachable from outside, see PublicAddrs
+	autoRegister bool // whether the checker registers itself on Start if not registered
+	health *HealthRegistry // the health info of the member nodes
+	events *EventBus // the changes of the checked nodes, for the streaming api
+	latencyThreshold time.Duration // latency above which an address is reported slow, 0 to disable
+
+	history *HistoryStore // the on disk probe history, nil if disabled
+
+	// probing round related
+	probeConcurrency int // max number of nodes probed in parallel
+	roundTimeout time.Duration // max duration of a probing round
 
 	// libp2p ping related
 	node host.Host // node is the libp2p node struct of the checker
 	ping *ping.PingService // the libp2p ping service
 
+	probers *ProberRegistry // the probers used to check the multi addrs
+
+	dryRun bool // whether the messages to the actor are only simulated
+	dryRunRegistered bool // whether the registration was simulated, the reports being simulated as registered
+	queue *MessageQueue // serializes the nonces of the messages sent by the checker
+	inflight map[ActorID]bool // the checkers with a report sent and not yet executed
+
+	source StateSource // loads the states of the actor, from the chain by default
+	watcher *ChainWatcher // follows the chain and holds the latest state of the actor
+	decisions *pendingDecisions // the report decisions that a reorg could still undo
+
 	rwLock sync.RWMutex
 	stop bool
+	cancel context.CancelFunc
 }
 
 func NewUptimeChecker(
@@ -65,6 +88,9 @@ func NewUptimeChecker(
 	if err != nil {
 		return UptimeChecker{}, err
 	}
+
+	probers := NewDefaultProberRegistry(node, ping)
+
 	return UptimeChecker {
 		api: api,
 
@@ -73,29 +99,80 @@ func NewUptimeChecker(
 		uptimeCheckerAddress: addr,
 
 		checkerAddresses: checkerAddresses,
-		nodeAddresses: make(map[ActorID]map[MultiAddr]HealtcheckInfo),
+		autoRegister: true,
+		health: NewHealthRegistry(),
+		events: NewEventBus(DEFAULT_EVENT_HISTORY),
 
 		node: node,
 		ping: ping,
 
+		probers: probers,
+
+		source: NewChainStateSource(api, addr),
+		watcher: NewChainWatcher(api, addr, self),
+		decisions: newPendingDecisions(),
+
+		queue: NewMessageQueue(api),
+		inflight: make(map[ActorID]bool),
+
+		probeConcurrency: DEFAULT_PROBE_CONCURRENCY,
+		roundTimeout: DEFAULT_ROUND_TIMEOUT,
+
 		stop: false,
 	}, nil
 }
 
 func (u *UptimeChecker) Start(ctx context.Context) error {
+	if u.api == nil {
+		// e.g. a simulation against a MemoryStateSource
+		log.Warnw("no lotus node, the checks run but no message is sent to the actor")
+	} else if err := u.checkWallet(ctx); err != nil {
+		return err
+	}
+
 	hasRegistered, err := u.HasRegistered(ctx)
 	if err != nil {
 		return err
 	}
 
-	if !hasRegistered {
+	if !hasRegistered && u.autoRegister {
 		if err := u.Register(ctx); err != nil {
 			return err
 		}
+		if u.dryRun {
+			log.Infow("dry run, registration simulated, the reports are simulated as registered")
+			u.rwLock.Lock()
+			u.dryRunRegistered = true
+			u.rwLock.Unlock()
+		}
+	} else if !hasRegistered {
+		// the checks still run, the reports are skipped until registered
+		log.Warnw("not registered with the actor and auto register disabled, register with register-checker", "actorId", u.self)
 	} else {
 		log.Infow("already registered with the actor, skip register")
+
+		if err := u.checkRegistered(ctx); err != nil {
+			return err
+		}
+	}
+
+	if u.history != nil {
+		if err := u.restoreHealthInfo(ctx); err != nil {
+			return err
+		}
+		go u.compactHistory(ctx)
 	}
 
+	// the loops are stopped once the watcher stops following the chain
+	ctx, cancel := context.WithCancel(ctx)
+	u.rwLock.Lock()
+	u.cancel = cancel
+	u.rwLock.Unlock()
+
+	go u.watcher.Run(ctx)
+
+	go u.reevaluateOnReorg(ctx)
+
 	go u.processReportedCheckers(ctx)
 
 	go u.monitorMemberNodes(ctx)
@@ -107,7 +184,7 @@ func (u *UptimeChecker) Start(ctx context.Context) error {
 
 // HasRegistered checks if the current checker has already registered itself in the actor
 func (u *UptimeChecker) HasRegistered(ctx context.Context) (bool, error) {
-	s, err := Load(ctx, u.api, u.uptimeCheckerAddress, u.self)
+	s, err := u.loadState(ctx, chainTypes.EmptyTSK)
 	log.Infow("Self actor id", "actorId", u.self)
 
 	if err != nil {
@@ -116,46 +193,132 @@ func (u *UptimeChecker) HasRegistered(ctx context.Context) (bool, error) {
 	return s.HasRegistered(u.self)
 }
 
+// checkRegistered refuses checkers registered with another peer id or other addresses than
+// the node, which the other checkers would fail to probe and vote out
+func (u *UptimeChecker) checkRegistered(ctx context.Context) error {
+	s, err := u.loadState(ctx, chainTypes.EmptyTSK)
+	if err != nil {
+		return err
+	}
+
+	info, err := s.GetCheckerInfo(u.self)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		return nil
+	}
+	if u.node == nil {
+		log.Warnw("no libp2p host, registration of the checker not checked")
+		return nil
+	}
+
+	if err := checkRegistration(info, u.node.ID(), u.checkerAddresses); err != nil {
+		return fmt.Errorf("%w, update it with edit-checker", err)
+	}
+	return nil
+}
+
 // Register registers the current checker to actor
 func (u *UptimeChecker) Register(ctx context.Context) error {
 	log.Infow("has yet to be registered with the actor, register now")
 
+	if u.api == nil || u.node == nil {
+		return fmt.Errorf("%w, register the checker in the state", ErrNoNode)
+	}
+
 	peerID := u.node.ID();
-	log.Infow("register new checker with peer id", "peerID", peerID.String())
+	log.Infow("register new checker with peer id", "peerID", peerID.String(), "addrs", u.checkerAddresses)
 
-	params, err := encodeJson(NodeInfo {
-		Id: peerID.String(),
-		Addresses: u.checkerAddresses,
-	})
-	if err != nil {
-		return err
+	if len(u.checkerAddresses) == 0 {
+		return fmt.Errorf("%w: set the public addresses of the checker", ErrNoAnnounceAddrs)
 	}
 
-	fromAddr, err := u.getWalletAddress(ctx)
+	client, err := u.actorClient(ctx)
 	if err != nil {
 		return err
 	}
 
-	return u.executeMsgAndWait(ctx, NEW_CHECKER_METHOD, fromAddr, params)
+	return client.NewChecker(ctx, NodeInfoPayload {
+		Id: peerID.String(),
+		Addresses: u.checkerAddresses,
+	})
 }
 
 // Reports to the actor that the checker is down
 func (u *UptimeChecker) ReportChecker(ctx context.Context, actor ActorID) error {
 	log.Infow("report checker as down", "checker", actor)
 
-	params, err := encodeJson(PeerReportPayload {
-		Checker: actor,
-	})
+	client, err := u.actorClient(ctx)
 	if err != nil {
 		return err
 	}
 
-	fromAddr, err := u.getWalletAddress(ctx)
-	if err != nil {
-		return err
-	}
+	return client.ReportChecker(ctx, actor)
+}
+
+// RegisterProber sets a custom prober for the multiaddr protocol, e.g. a chain head freshness
+// check for lotus nodes. Existing probers of the same protocol are replaced.
+func (u *UptimeChecker) RegisterProber(protocol string, p Prober) {
+	u.probers.Register(protocol, p)
+}
+
+// SetAutoRegister sets whether the checker registers itself on Start, the default. Otherwise
+// it must be registered beforehand, its reports being skipped until it is.
+func (u *UptimeChecker) SetAutoRegister(autoRegister bool) {
+	u.autoRegister = autoRegister
+}
+
+// SetWallet sets the wallet signing the messages, instead of the one at the wallet index. The
+// actor id of the checker is resolved from it on Start.
+func (u *UptimeChecker) SetWallet(wallet address.Address) {
+	u.wallet = wallet
+}
+
+// SetHistoryStore enables the persistence of the probe results. The recent history is
+// reloaded on Start.
+func (u *UptimeChecker) SetHistoryStore(history *HistoryStore) {
+	u.history = history
+}
+
+// SetLatencyThreshold sets the latency above which the addresses of the members are reported
+// as slow in the events. 0 disables the latency events.
+func (u *UptimeChecker) SetLatencyThreshold(threshold time.Duration) {
+	u.latencyThreshold = threshold
+}
+
+// SetStateSource sets where the states of the actor are loaded from, e.g. a
+// MemoryStateSource to run the checker without a lotus node
+func (u *UptimeChecker) SetStateSource(source StateSource) {
+	u.source = source
+	u.watcher.SetStateSource(source)
+}
+
+// SetConfidence sets how many epochs behind the head the state used to report checkers is,
+// so that short reorgs cannot make the checker vote on state that disappears
+func (u *UptimeChecker) SetConfidence(confidence abi.ChainEpoch) {
+	u.watcher.SetConfidence(confidence)
+}
 
-	return u.executeMsgAndWait(ctx, REPORT_CHECKER_METHOD, fromAddr, params)
+// SetDryRun sets whether the registration and reports are only simulated, never sent
+func (u *UptimeChecker) SetDryRun(dryRun bool) {
+	u.dryRun = dryRun
+}
+
+// SetProbeConcurrency sets the max number of nodes probed in parallel in each round
+func (u *UptimeChecker) SetProbeConcurrency(concurrency int) {
+	u.probeConcurrency = concurrency
+}
+
+// SetRoundTimeout sets the max duration of a probing round. Nodes not probed within the
+// round are skipped until the next one. It is at least MIN_ROUND_TIMEOUT, so that a probe
+// of a hung node times out, and is recorded offline, before the round ends.
+func (u *UptimeChecker) SetRoundTimeout(timeout time.Duration) {
+	if timeout < MIN_ROUND_TIMEOUT {
+		log.Warnw("round timeout shorter than the probe timeout, using the min", "timeout", timeout, "min", MIN_ROUND_TIMEOUT)
+		timeout = MIN_ROUND_TIMEOUT
+	}
+	u.roundTimeout = timeout
 }
 
 // IsStop checks if the up time checker should stop running
@@ -170,77 +333,392 @@ func (u *UptimeChecker) Stop() {
 	u.rwLock.Lock()
 	defer u.rwLock.Unlock()
 	u.stop = true
+	if u.cancel != nil {
+		u.cancel()
+	}
+	u.queue.Close()
 }
 
 func (u *UptimeChecker) CheckChecker(ctx context.Context, actorID ActorID, addrs *[]MultiAddr) error {
 	infos := u.multiAddrsUp(ctx, addrs)
-	
-	if !allUp(&infos) {
+	return u.reportIfDown(ctx, actorID, &infos)
+}
+
+func (u *UptimeChecker) CheckMember(ctx context.Context, actorID ActorID, addrs *[]MultiAddr) error {
+	infos := u.multiAddrsUp(ctx, addrs)
+	return u.recordMemberHealthInfo(ctx, actorID, &infos)
+}
+
+// /// =================== Private Functions ====================
+
+// Reports the checker to the actor if any of its addresses is down
+// The decision is based on the confirmed state, confidence epochs behind the head, and is
+// re-evaluated if a reorg reverts the tipset it was taken at.
+func (u *UptimeChecker) reportIfDown(ctx context.Context, actorID ActorID, infos *[]UpInfo) error {
+	if !allUp(infos) {
 		log.Warnw("actor down, report now", "actorID", actorID)
 
-		state, err := Load(ctx, u.api, u.uptimeCheckerAddress, u.self)
+		state, ts, err := u.confirmedState(ctx)
 		if err != nil {
 			log.Errorw("cannot load state", "err", err)
 			return err
 		}
 
 		hasVoted, err := state.HasVotedReportedPeer(actorID)
+		if err != nil {
+			return err
+		}
+
+		// the vote and the registration of this checker may not be confirmed yet
+		latest := u.watcher.State()
+		if latest == nil {
+			latest = state
+		}
 		if !hasVoted {
-			return u.ReportChecker(ctx, actorID)
+			hasVoted, err = latest.HasVotedReportedPeer(actorID)
+			if err != nil {
+				return err
+			}
 		}
-		log.Debugw("has already reported actor", "actor", actorID)
-		
+
+		u.decisions.add(actorID, ts.Height(), u.watcher.Confidence())
+
+		if hasVoted {
+			log.Debugw("has already reported actor", "actor", actorID)
+			return nil
+		}
+
+		// only checkers can report, the actor would reject it with NotCaller
+		registered, err := latest.HasRegistered(u.self)
+		if err != nil {
+			return err
+		}
+		if !registered && !u.isDryRunRegistered() {
+			log.Warnw("not registered as a checker, cannot report", "actor", actorID)
+			return nil
+		}
+
+		if u.isInflight(actorID) {
+			log.Debugw("report already in flight", "actor", actorID)
+			return nil
+		}
+
+		if u.api == nil {
+			log.Warnw("no lotus node, report not sent", "actor", actorID)
+			return nil
+		}
+
+		client, err := u.actorClient(ctx)
+		if err != nil {
+			return err
+		}
+
+		// the checker loop does not wait for the vote to be executed
+		log.Infow("report checker as down", "checker", actorID)
+		pending, err := client.ReportCheckerAsync(ctx, actorID)
+		if err != nil || pending == nil {
+			return u.handleReportResult(state, actorID, err)
+		}
+
+		// the report is no longer awaited once the checker stops
+		u.setInflight(actorID, true)
+		go func() {
+			defer u.setInflight(actorID, false)
+			if err := u.handleReportResult(state, actorID, client.Await(ctx, pending)); err != nil && ctx.Err() == nil {
+				log.Errorw("cannot report checker", "actor", actorID, "cid", pending.Cid(), "err", err)
+			}
+		}()
 	}
 	return nil
 }
 
-func (u *UptimeChecker) CheckMember(ctx context.Context, actorID ActorID, addrs *[]MultiAddr) error {
-	infos := u.multiAddrsUp(ctx, addrs)
-	return u.recordMemberHealthInfo(actorID, &infos, addrs)
+// handleReportResult records the vote on the checker once the actor accepted it, the errors
+// meaning that no vote is needed anymore being dropped
+func (u *UptimeChecker) handleReportResult(state *CacheState, actorID ActorID, err error) error {
+	switch {
+	case errors.Is(err, ErrAlreadyVoted):
+		log.Debugw("vote already recorded by the actor", "actor", actorID)
+		err = nil
+	case errors.Is(err, ErrNotCaller) && u.isDryRunRegistered():
+		// the simulated registration is not in the state the report is simulated against
+		log.Infow("dry run, report would be sent once registered", "actor", actorID)
+		return nil
+	case errors.Is(err, ErrNotCaller):
+		// removed since the confirmed state, e.g. evicted by the other checkers
+		log.Errorw("no longer registered as a checker, report rejected", "actor", actorID, "err", err)
+		return nil
+	}
+	if err == nil && !u.dryRun {
+		// the states share the votes, so that the next ones skip the checker
+		state.recordVoted(actorID)
+	}
+	return err
 }
 
-// /// =================== Private Functions ====================
+func (u *UptimeChecker) isDryRunRegistered() bool {
+	u.rwLock.RLock()
+	defer u.rwLock.RUnlock()
+	return u.dryRunRegistered
+}
+
+func (u *UptimeChecker) isInflight(actorID ActorID) bool {
+	u.rwLock.RLock()
+	defer u.rwLock.RUnlock()
+	return u.inflight[actorID]
+}
 
-// Records and aggregate on the health info of membership nodes
-func (u *UptimeChecker) recordMemberHealthInfo(actorID ActorID, upInfos *[]UpInfo, addrs *[]MultiAddr) error {
-	healthInfos, ok := u.nodeAddresses[actorID]
-	if !ok {
-		healthInfos = make(map[MultiAddr]HealtcheckInfo, len(*upInfos))
+func (u *UptimeChecker) setInflight(actorID ActorID, inflight bool) {
+	u.rwLock.Lock()
+	defer u.rwLock.Unlock()
+	if inflight {
+		u.inflight[actorID] = true
+	} else {
+		delete(u.inflight, actorID)
 	}
+}
 
-	for i, addr := range(*addrs) {
-		val, ok := healthInfos[addr]
-		if !ok {
-			val = HealtcheckInfo{
-				HealtcheckAddr: addr,
-				
-				AvgLatency: (*upInfos)[i].latency,
-				LatencyCounts: 1,
-				
-				IsOnline: (*upInfos)[i].isOnline,
-				Latency: (*upInfos)[i].latency,
-				LastChecked: (*upInfos)[i].checkedTime,
+// Re-evaluates the report decisions taken on tipsets reverted by a reorg
+func (u *UptimeChecker) reevaluateOnReorg(ctx context.Context) {
+	reorgs, cancel := u.watcher.SubscribeReorgs(REORG_BUFFER)
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case reverted := <-reorgs:
+			state := u.watcher.State()
+			if state == nil {
+				continue
+			}
+
+			for _, actorID := range u.decisions.takeReverted(reverted) {
+				log.Infow("re-evaluating report decision after reorg", "checker", actorID, "reverted", reverted)
+
+				addrs, err := state.ListCheckerMultiAddrs(actorID)
+				if err != nil {
+					log.Errorw("cannot list checker multi addrs", "checker", actorID, "err", err)
+					continue
+				}
+				if addrs == nil {
+					// no longer a checker on the new chain
+					continue
+				}
+
+				if err := u.CheckChecker(ctx, actorID, addrs); err != nil {
+					log.Errorw("cannot check checker", "checker", actorID, "err", err)
+				}
+			}
+		}
+	}
+}
+
+// Records and aggregate on the health info of membership nodes. The probes are keyed by their
+// address, as the addresses shared with this checker are not probed.
+func (u *UptimeChecker) recordMemberHealthInfo(ctx context.Context, actorID ActorID, upInfos *[]UpInfo) error {
+	records := make([]ProbeRecord, 0, len(*upInfos) + 1)
+	events := make([]Event, 0)
+
+	u.health.Update(actorID, func(member *MemberHealthInfo) {
+		isOnline := false
+		checkedTime := uint64(0)
+
+		// members restored from the history or probed before have been checked
+		wasChecked := member.LastChecked != 0
+		wasOnline := member.IsOnline
+
+		for _, upInfo := range(*upInfos) {
+			addr := upInfo.addr
+
+			val, ok := member.Addresses[addr]
+			if !ok {
+				val = newHealtcheckInfo(addr)
+			}
+
+			val.IsOnline = upInfo.isOnline
+			val.Latency = upInfo.latency
+			val.LastChecked = upInfo.checkedTime
+			val.StatusCode = upInfo.statusCode
+			val.BodySize = upInfo.bodySize
+
+			val.recordLatency(upInfo)
+
+			if e, ok := u.latencyEvent(actorID, &val, upInfo); ok {
+				events = append(events, e)
+			}
+
+			val.availability.add(upInfo.checkedTime, upInfo.isOnline)
+			val.Uptime = val.availability.uptimeInfo(int64(upInfo.checkedTime))
+
+			member.Addresses[addr] = val
+
+			isOnline = isOnline || upInfo.isOnline
+			if upInfo.checkedTime > checkedTime {
+				checkedTime = upInfo.checkedTime
+			}
+
+			records = append(records, ProbeRecord{
+				Actor: actorID,
+				Addr: addr,
+				Timestamp: upInfo.checkedTime,
+				IsOnline: upInfo.isOnline,
+				Latency: upInfo.latency,
+			})
+		}
+
+		if len(*upInfos) > 0 {
+			member.IsOnline = isOnline
+			member.LastChecked = checkedTime
+
+			member.availability.add(checkedTime, isOnline)
+			member.Uptime = member.availability.uptimeInfo(int64(checkedTime))
+
+			if !wasChecked || wasOnline != isOnline {
+				eventType := EVENT_MEMBER_DOWN
+				if isOnline {
+					eventType = EVENT_MEMBER_UP
+				}
+				events = append(events, Event{Type: eventType, Timestamp: int64(checkedTime), Actor: actorID})
 			}
-		} else {
-			val.IsOnline = (*upInfos)[i].isOnline
-			val.Latency = (*upInfos)[i].latency
-			val.LastChecked = (*upInfos)[i].checkedTime
 
-			// moving average calculation
-			newCount := val.AvgLatency + 1
-			val.AvgLatency = val.AvgLatency * val.AvgLatency / newCount + val.Latency / newCount
-			val.AvgLatency = newCount
+			records = append(records, ProbeRecord{
+				Actor: actorID,
+				Addr: MEMBER_RECORD_ADDR,
+				Timestamp: checkedTime,
+				IsOnline: isOnline,
+			})
 		}
-		healthInfos[addr] = val
+
+		observeMemberHealth(actorID, member)
+	})
+
+	for _, record := range records {
+		u.recordHistory(ctx, record)
+	}
+
+	for _, e := range events {
+		u.events.Publish(e)
+	}
+
+	return nil
+}
+
+// Returns the event to publish if the latency of the address crossed the threshold. Offline
+// probes have no latency and leave the address as is.
+func (u *UptimeChecker) latencyEvent(actorID ActorID, val *HealtcheckInfo, upInfo UpInfo) (Event, bool) {
+	if u.latencyThreshold == 0 || !upInfo.isOnline {
+		return Event{}, false
+	}
+
+	isHigh := upInfo.latency > uint64(u.latencyThreshold)
+	if isHigh == val.latencyHigh {
+		return Event{}, false
+	}
+	val.latencyHigh = isHigh
+
+	eventType := EVENT_LATENCY_NORMAL
+	if isHigh {
+		eventType = EVENT_LATENCY_HIGH
+	}
+	return Event{
+		Type: eventType,
+		Timestamp: int64(upInfo.checkedTime),
+		Actor: actorID,
+		Addr: val.HealtcheckAddr,
+		Latency: upInfo.latency,
+	}, true
+}
+
+// Writes the probe result through to the history store, if enabled
+func (u *UptimeChecker) recordHistory(ctx context.Context, record ProbeRecord) {
+	if u.history == nil {
+		return
+	}
+
+	if err := u.history.Record(ctx, record); err != nil {
+		log.Errorw("cannot record probe history", "actor", record.Actor, "addr", record.Addr, "err", err)
+	}
+}
+
+// Rebuilds the uptime windows and latency statistics of the members from the probe history
+func (u *UptimeChecker) restoreHealthInfo(ctx context.Context) error {
+	now := time.Now().Unix()
+	since := uint64(now - int64(UPTIME_WINDOW_MONTH / time.Second))
+
+	aggregates, records, err := u.history.LoadSince(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range aggregates {
+		a := a
+		u.health.Update(a.Actor, func(member *MemberHealthInfo) {
+			if a.Addr == MEMBER_RECORD_ADDR {
+				member.availability.addCounts(a.Start, a.Checks, a.Online)
+				member.Uptime = member.availability.uptimeInfo(now)
+				return
+			}
+
+			val, ok := member.Addresses[a.Addr]
+			if !ok {
+				val = newHealtcheckInfo(a.Addr)
+			}
+			val.availability.addCounts(a.Start, a.Checks, a.Online)
+			val.Uptime = val.availability.uptimeInfo(now)
+			member.Addresses[a.Addr] = val
+		})
 	}
 
-	u.nodeAddresses[actorID] = healthInfos
+	for _, r := range records {
+		r := r
+		u.health.Update(r.Actor, func(member *MemberHealthInfo) {
+			if r.Addr == MEMBER_RECORD_ADDR {
+				member.IsOnline = r.IsOnline
+				member.LastChecked = r.Timestamp
+				member.availability.add(r.Timestamp, r.IsOnline)
+				member.Uptime = member.availability.uptimeInfo(now)
+				return
+			}
+
+			val, ok := member.Addresses[r.Addr]
+			if !ok {
+				val = newHealtcheckInfo(r.Addr)
+			}
+			val.IsOnline = r.IsOnline
+			val.Latency = r.Latency
+			val.LastChecked = r.Timestamp
+			val.recordLatency(UpInfo{isOnline: r.IsOnline, latency: r.Latency, checkedTime: r.Timestamp})
+			val.availability.add(r.Timestamp, r.IsOnline)
+			val.Uptime = val.availability.uptimeInfo(now)
+			member.Addresses[r.Addr] = val
+		})
+	}
+
+	log.Infow("restored probe history", "aggregates", len(aggregates), "probes", len(records))
 
 	return nil
 }
 
+// Periodically downsamples and prunes the probe history
+func (u *UptimeChecker) compactHistory(ctx context.Context) {
+	for {
+		if u.IsStop() {
+			break
+		}
+
+		if err := u.history.Compact(ctx, time.Now()); err != nil {
+			log.Errorw("cannot compact probe history", "err", err)
+		}
+
+		u.sleep(HISTORY_COMPACT_INTERVAL)
+	}
+}
+
+// Probes the addresses concurrently, so that a node is probed within a single PING_TIMEOUT
+// however many of its addresses hang. An address whose probe is cut by the deadline of the
+// round is down, as for its own timeout.
 func (u *UptimeChecker) multiAddrsUp(ctx context.Context, addrs *[]MultiAddr) []UpInfo {
-	upInfos := make([]UpInfo, 0)
+	toCheck := make([]MultiAddr, 0, len(*addrs))
 	for _, addr := range(*addrs) {
 
 		isCheck := true
@@ -254,210 +732,397 @@ func (u *UptimeChecker) multiAddrsUp(ctx context.Context, addrs *[]MultiAddr) []
 		if !isCheck {
 			continue
 		}
+		toCheck = append(toCheck, addr)
+	}
 
-		upInfos = append(upInfos, u.isUp(ctx, addr))
+	upInfos := make([]UpInfo, len(toCheck))
+	var wg sync.WaitGroup
+	for i, addr := range toCheck {
+		wg.Add(1)
+		go func(i int, addr MultiAddr) {
+			defer wg.Done()
+			upInfos[i] = u.isUp(ctx, addr)
+		}(i, addr)
 	}
+	wg.Wait()
+
 	return upInfos
 }
 
+// processReportedCheckers checks the reported checkers not voted yet every time the state of
+// the actor changes, so that new reports are handled right away, and at least every
+// DEFAULT_SLEEP_SECONDS otherwise
 func (u *UptimeChecker) processReportedCheckers(ctx context.Context) error {
+	changed, cancel := u.watcher.Subscribe()
+	defer cancel()
+
+	var reported map[ActorID]bool
+
 	for {
 		if u.IsStop() {
 			break
 		}
 
-		state, err := Load(ctx, u.api, u.uptimeCheckerAddress, u.self)
+		state, err := u.watcher.Wait(ctx)
 		if err != nil {
-			log.Errorw("cannot load state", "err", err)
-			continue
+			break
 		}
 
-		listToCheck, err := state.ListReportedCheckerNotVoted()
-		if err != nil {
-			log.Errorw("cannot list repored checkers not voted", "err", err)
-			continue
-		}
+		reported = u.checkReportedCheckers(ctx, state, reported)
 
-		for toCheckPeerID, addrs := range(*listToCheck) {
-			u.CheckChecker(ctx, toCheckPeerID, addrs)
+		select {
+		case <-ctx.Done():
+		case <-changed:
+		case <-time.After(DEFAULT_SLEEP_SECONDS):
 		}
-
-		u.sleep(DEFAULT_SLEEP_SECONDS)
 	}
 
 	return nil
 }
 
+// checkReportedCheckers probes the reported checkers this checker has not voted for yet and
+// returns the set of reported checkers
+func (u *UptimeChecker) checkReportedCheckers(ctx context.Context, state *CacheState, reported map[ActorID]bool) map[ActorID]bool {
+	offline, err := state.GetOfflineCheckers()
+	if err != nil {
+		log.Errorw("cannot list offline checkers", "err", err)
+		return reported
+	}
+	reported = u.publishSetChanges(reported, offline, EVENT_CHECKER_REPORTED)
+
+	listToCheck, err := state.ListReportedCheckerNotVoted()
+	if err != nil {
+		log.Errorw("cannot list repored checkers not voted", "err", err)
+		return reported
+	}
+
+	ids := make([]ActorID, 0, len(*listToCheck))
+	for toCheckPeerID := range(*listToCheck) {
+		ids = append(ids, toCheckPeerID)
+	}
+
+	start := time.Now()
+	u.checkCheckersInRound(ctx, ids, *listToCheck)
+	observeRound(LOOP_REPORTED_CHECKERS, start)
+
+	return reported
+}
+
+// monitorMemberNodes probes all the members every DEFAULT_SLEEP_SECONDS. When the state
+// changes in between, only the members added or edited are probed right away and the
+// removed ones are dropped.
 func (u *UptimeChecker) monitorMemberNodes(ctx context.Context) error {
+	changed, cancel := u.watcher.Subscribe()
+	defer cancel()
+
+	members := newNodeSet(false)
+	next := time.Now()
+
 	for {
 		if u.IsStop() {
 			break
 		}
 
-		state, err := Load(ctx, u.api, u.uptimeCheckerAddress, u.self)
+		state, err := u.watcher.Wait(ctx)
 		if err != nil {
-			log.Errorw("cannot load state", "err", err)
-			continue
+			break
 		}
 
-		listToCheck, err := state.ListMembers()
+		changes, err := members.sync(state)
 		if err != nil {
 			log.Errorw("cannot list members", "err", err)
+			u.sleep(DEFAULT_SLEEP_SECONDS)
 			continue
 		}
 
-		for _, toCheckActorID := range listToCheck {
-			addrs, err := state.ListMemberMultiAddrs(toCheckActorID)
-			if err != nil {
-				log.Errorw("cannot list member multi addrs", "actor", toCheckActorID, "err", err)
-				continue
+		toProbe := make([]ActorID, 0)
+		for _, change := range changes {
+			switch change.Type {
+			case CHANGE_REMOVED:
+				log.Infow("member removed", "actor", change.Actor)
+				u.forgetMember(change.Actor)
+			case CHANGE_EDITED:
+				u.pruneMemberAddrs(change.Actor, change.After.Addresses)
+				toProbe = append(toProbe, change.Actor)
+			case CHANGE_ADDED:
+				toProbe = append(toProbe, change.Actor)
 			}
+		}
 
-			log.Debugw("member info", "actor", toCheckActorID, "addrs", addrs)
-
-			u.CheckMember(ctx, toCheckActorID, addrs)
+		if !time.Now().Before(next) {
+			u.probeMembers(ctx, members, members.ids())
+			next = time.Now().Add(DEFAULT_SLEEP_SECONDS)
+		} else if len(toProbe) > 0 {
+			u.probeMembers(ctx, members, toProbe)
 		}
 
-		u.sleep(DEFAULT_SLEEP_SECONDS)
+		select {
+		case <-ctx.Done():
+		case <-changed:
+		case <-time.After(time.Until(next)):
+		}
 	}
 
 	return nil
 }
 
+// probeMembers probes the members within a single round and records their health info
+func (u *UptimeChecker) probeMembers(ctx context.Context, members *nodeSet, listToCheck []ActorID) {
+	start := time.Now()
+	runRound(ctx, u.probeConcurrency, u.roundTimeout, PING_TIMEOUT, len(listToCheck), func(rctx context.Context, i int) {
+		toCheckActorID := listToCheck[i]
+
+		addrs, ok := members.addrs[toCheckActorID]
+		if !ok {
+			return
+		}
+
+		log.Debugw("member info", "actor", toCheckActorID, "addrs", addrs)
+
+		infos := u.multiAddrsUp(rctx, addrs)
+		if rctx.Err() != nil {
+			log.Warnw("round deadline reached while member was probed, unanswered addresses are down", "actor", toCheckActorID)
+		}
+
+		u.recordMemberHealthInfo(ctx, toCheckActorID, &infos)
+	})
+	observeRound(LOOP_MEMBERS, start)
+}
+
+// Drops the health info of a member removed from the actor
+func (u *UptimeChecker) forgetMember(actorID ActorID) {
+	if member, ok := u.health.Member(actorID); ok {
+		forgetMemberHealth(actorID, &member)
+	}
+	u.health.Remove(actorID)
+}
+
+// Drops the health info of the addresses no longer advertised by an edited member
+func (u *UptimeChecker) pruneMemberAddrs(actorID ActorID, addrs []MultiAddr) {
+	if _, ok := u.health.Member(actorID); !ok {
+		return
+	}
+
+	u.health.Update(actorID, func(member *MemberHealthInfo) {
+		for addr := range member.Addresses {
+			if !containsAddr(addrs, addr) {
+				forgetAddressHealth(actorID, addr)
+				delete(member.Addresses, addr)
+			}
+		}
+	})
+}
+
+// monitorCheckerNodes probes all the checkers every DEFAULT_SLEEP_SECONDS, the checkers
+// added or edited in between are probed right away
 func (u *UptimeChecker) monitorCheckerNodes(ctx context.Context) error {
+	changed, cancel := u.watcher.Subscribe()
+	defer cancel()
+
+	checkers := newNodeSet(true)
+	first := true
+	next := time.Now()
+
 	for {
 		if u.IsStop() {
 			break
 		}
 
-		state, err := Load(ctx, u.api, u.uptimeCheckerAddress, u.self)
+		state, err := u.watcher.Wait(ctx)
 		if err != nil {
-			log.Errorw("cannot load state", "err", err)
-			continue
+			break
 		}
 
-		listToCheck, err := state.ListCheckers()
+		changes, err := checkers.sync(state)
 		if err != nil {
-			log.Errorw("cannot list members", "err", err)
+			log.Errorw("cannot list checkers", "err", err)
+			u.sleep(DEFAULT_SLEEP_SECONDS)
 			continue
 		}
 
-		log.Infow("list of checkers registered", "checkers", listToCheck)
-
-		for _, toCheckPeerID := range listToCheck {
-			addrs, err := state.ListCheckerMultiAddrs(toCheckPeerID)
-			if err != nil {
-				log.Errorw("cannot list member multi addrs", "peer", toCheckPeerID, "err", err)
-				continue
+		toProbe := make([]ActorID, 0)
+		for _, change := range changes {
+			switch change.Type {
+			case CHANGE_REMOVED:
+				log.Infow("checker removed", "actor", change.Actor)
+				u.events.Publish(Event{Type: EVENT_CHECKER_REMOVED, Actor: change.Actor})
+			case CHANGE_ADDED, CHANGE_EDITED:
+				if !first {
+					toProbe = append(toProbe, change.Actor)
+				}
 			}
+		}
+		first = false
 
-			log.Debugw("toCheckPeerID addrs", "toCheckPeerID", toCheckPeerID, "addrs", addrs)
+		if !time.Now().Before(next) {
+			listToCheck := checkers.ids()
+			log.Infow("list of checkers registered", "checkers", listToCheck)
 
-			err = u.CheckChecker(ctx, toCheckPeerID, addrs)
-			if err != nil {
-				log.Errorw("cannot check checker", "peer", toCheckPeerID, "err", err)
-			}
+			start := time.Now()
+			u.checkCheckersInRound(ctx, listToCheck, checkers.addrs)
+			observeRound(LOOP_CHECKERS, start)
+
+			next = time.Now().Add(DEFAULT_SLEEP_SECONDS)
+		} else if len(toProbe) > 0 {
+			u.checkCheckersInRound(ctx, toProbe, checkers.addrs)
 		}
 
-		u.sleep(DEFAULT_SLEEP_SECONDS)
+		select {
+		case <-ctx.Done():
+		case <-changed:
+		case <-time.After(time.Until(next)):
+		}
 	}
 
 	return nil
 }
 
-func (u *UptimeChecker) sleep(seconds time.Duration) {
-	time.Sleep(seconds)
+// publishSetChanges publishes the added event for the actors that entered the set since the
+// previous iteration of a loop. Nothing is published on the first iteration, when previous is nil.
+func (u *UptimeChecker) publishSetChanges(previous map[ActorID]bool, current []ActorID, added string) map[ActorID]bool {
+	next := make(map[ActorID]bool, len(current))
+	for _, actorID := range current {
+		next[actorID] = true
+		if previous != nil && !previous[actorID] {
+			u.events.Publish(Event{Type: added, Actor: actorID})
+		}
+	}
+	return next
 }
 
-// executeMsgAndWait executes the method with given params and waits for the message to be executed
-func (u *UptimeChecker) executeMsgAndWait(ctx context.Context, method uint32, from address.Address, params []byte) error {
-	smsg, err := u.executeMsg(ctx, method, from, params)
-	if err != nil {
-		return err
+// checkCheckersInRound probes the checkers concurrently within a single round and reports
+// the ones that are down once the round is over, including the ones that did not answer
+// before the round deadline.
+func (u *UptimeChecker) checkCheckersInRound(ctx context.Context, ids []ActorID, addrs map[ActorID]*[]MultiAddr) {
+	infos := make([][]UpInfo, len(ids))
+	probed := make([]bool, len(ids))
+
+	runRound(ctx, u.probeConcurrency, u.roundTimeout, PING_TIMEOUT, len(ids), func(rctx context.Context, i int) {
+		a, ok := addrs[ids[i]]
+		if !ok || a == nil {
+			return
+		}
+
+		infos[i] = u.multiAddrsUp(rctx, a)
+		probed[i] = true
+	})
+
+	for i, toCheckPeerID := range ids {
+		if !probed[i] {
+			continue
+		}
+
+		if err := u.reportIfDown(ctx, toCheckPeerID, &infos[i]); err != nil {
+			log.Errorw("cannot check checker", "peer", toCheckPeerID, "err", err)
+		}
 	}
+}
 
-	log.Infow("waiting for message to execute...")
-	return u.wait(ctx, smsg)
+func (u *UptimeChecker) sleep(seconds time.Duration) {
+	time.Sleep(seconds)
 }
 
-func (u *UptimeChecker) executeMsg(ctx context.Context, method uint32, from address.Address, params []byte) (*chainTypes.SignedMessage, error) {
-	msg := &chainTypes.Message{
-		To:     u.uptimeCheckerAddress,
-		From:   from,
-		Value:  big.Zero(),
-		Method: abi.MethodNum(method),
-		Params: params,
+// actorClient returns the client of the actor sending from the wallet of the checker
+func (u *UptimeChecker) actorClient(ctx context.Context) (*ActorClient, error) {
+	if u.api == nil {
+		return nil, ErrNoNode
 	}
-	return u.api.MpoolPushMessage(ctx, msg, nil)
-}
 
-func (u *UptimeChecker) wait(ctx context.Context, smsg *chainTypes.SignedMessage) (error) {
-	wait, err := u.api.StateWaitMsg(ctx, smsg.Cid(), 0)
+	from, err := u.getWalletAddress(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	client := NewActorClient(u.api, u.uptimeCheckerAddress, from)
+	client.SetQueue(u.queue)
+	client.SetDryRun(u.dryRun)
+	return client, nil
+}
 
-	// check it executed successfully
-	if wait.Receipt.ExitCode != 0 {
-		return fmt.Errorf("actor execution failed")
+// Checks is up and also record the latency
+func (u *UptimeChecker) isUp(ctx context.Context, addr MultiAddr) UpInfo {
+	info := u.probers.Probe(ctx, addr)
+	info.addr = addr
+	observeProbe(info)
+	return info
+}
+
+// confirmedState returns the state confidence epochs behind the head and its tipset, loading
+// it from the chain of the watcher if the watcher has not loaded it yet
+func (u *UptimeChecker) confirmedState(ctx context.Context) (*CacheState, *chainTypes.TipSet, error) {
+	if state, ts := u.watcher.Confirmed(); state != nil {
+		return state, ts, nil
 	}
 
-	return nil
-}
+	chain := u.watcher.Chain()
+	if chain == nil {
+		return nil, nil, ErrNoChain
+	}
 
-// Checks is up and also record the latency
-func (u *UptimeChecker) isUp(ctx context.Context, addrStr MultiAddr) UpInfo {
-	upInfo := UpInfo{
-		isOnline: false,
-		latency: uint64(0),
-		checkedTime: uint64(time.Now().Unix()),
+	head, err := chain.ChainHead(ctx)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	addr, err := libp2pMultiaddr.NewMultiaddr(addrStr)
+	height := head.Height() - u.watcher.Confidence()
+	if height < 0 {
+		height = 0
+	}
+	ts, err := chain.ChainGetTipSetByHeight(ctx, height, head.Key())
 	if err != nil {
-		log.Errorw("cannot parse multi addr", "addr", addr)
-		return upInfo
+		return nil, nil, err
 	}
 
-	peer, err := peerstore.AddrInfoFromP2pAddr(addr)
+	s, err := u.loadState(ctx, ts.Key())
 	if err != nil {
-		log.Errorw("cannot add multi addr", "addr", addr)
-		return upInfo
+		return nil, nil, err
 	}
+	return s, ts, nil
+}
 
-	log.Debugw("addr for peer", "peer", peer)
+// currentState returns the latest state followed by the watcher, loading it from the node
+// if the watcher has not loaded it yet
+func (u *UptimeChecker) currentState(ctx context.Context) (*CacheState, error) {
+	if state := u.watcher.State(); state != nil {
+		return state, nil
+	}
 
-	now := time.Now()
+	return u.loadState(ctx, chainTypes.EmptyTSK)
+}
 
-	cctx, _ := context.WithTimeout(ctx, PING_TIMEOUT)
-	if err := u.node.Connect(cctx, *peer); err != nil {
-		log.Errorw("cannot connect to multi addr", "peer", peer.ID, "err", err, "addr", addr)
-		return upInfo
+// loadState loads the state of the actor at the tipset from the state source
+func (u *UptimeChecker) loadState(ctx context.Context, tsk chainTypes.TipSetKey) (*CacheState, error) {
+	head, err := u.source.Head(ctx, tsk)
+	if err != nil {
+		stateLoadFailures.Inc()
+		return nil, err
 	}
 
-	ch := u.ping.Ping(cctx, peer.ID)
-	res := <-ch
-	log.Debugw("got ping response!", "RTT:", res.RTT, "res", res)
+	s, err := u.source.Load(ctx, head)
+	if err != nil {
+		stateLoadFailures.Inc()
+		return nil, err
+	}
+	return u.watcher.newState(s), nil
+}
 
-	upInfo.isOnline = true
-	upInfo.checkedTime = uint64(time.Now().Unix())
-	upInfo.latency = uint64(time.Since(now))
+// Health returns the registry holding the health info of the member nodes
+func (u *UptimeChecker) Health() *HealthRegistry {
+	return u.health
+}
 
-	return upInfo
+// Events returns the bus of the changes of the checked nodes
+func (u *UptimeChecker) Events() *EventBus {
+	return u.events
 }
 
-func (u *UptimeChecker) NodeInfo() map[ActorID]map[MultiAddr]HealtcheckInfo {
-	return u.nodeAddresses
+func (u *UptimeChecker) NodeInfo() map[ActorID]MemberHealthInfo {
+	return u.health.Snapshot()
 }
 
 func (u *UptimeChecker) NodeInfoJsonString() (string, error) {
-	log.Debugw("node map", "nodes",  u.nodeAddresses)
+	data := u.health.Snapshot()
 
-	data := make(map[ActorID]map[MultiAddr]HealtcheckInfo, 0)
-
-	for k, v := range u.nodeAddresses {
-		data[k] = v
-	}
+	log.Debugw("node map", "nodes", data)
 
 	bytes, err := encodeJson(data)
 	if err != nil {
@@ -467,16 +1132,41 @@ func (u *UptimeChecker) NodeInfoJsonString() (string, error) {
 	return string(bytes), nil
 }
 
+// getWalletAddress returns the wallet set with SetWallet, otherwise the one at the wallet index
 func (u *UptimeChecker) getWalletAddress(ctx context.Context) (address.Address, error) {
+	if u.wallet != address.Undef {
+		return u.wallet, nil
+	}
 	return getWalletAddressFromIndex(u.api, ctx, u.walletIndex)
 }
 
-func getWalletAddressFromIndex(api v0api.FullNode, ctx context.Context, index int) (address.Address, error) {
-	walletList, err := api.WalletList(ctx)
+// checkWallet checks that the wallet can sign, and pins it so that the wallet index is not
+// resolved again. The actor id of the checker is the one of the wallet; if set, it must
+// match it.
+func (u *UptimeChecker) checkWallet(ctx context.Context) error {
+	wallet, err := u.getWalletAddress(ctx)
+	if err != nil {
+		return err
+	}
+	if err := ValidateWallet(ctx, u.api, wallet); err != nil {
+		return err
+	}
+
+	actorID := u.self
+	if actorID != 0 {
+		err = CheckWalletActor(ctx, u.api, wallet, actorID)
+	} else {
+		actorID, err = LookupWalletActor(ctx, u.api, wallet)
+	}
 	if err != nil {
-		return address.Address{}, nil
+		return err
 	}
-	return walletList[index], nil
+
+	log.Infow("signing with wallet", "wallet", wallet, "actorId", actorID)
+	u.wallet = wallet
+	u.self = actorID
+	u.watcher.setSelf(actorID)
+	return nil
 }
 
 func NewMember(
@@ -487,7 +1177,11 @@ func NewMember(
 	peerId string,
 	walletIndex int,
 ) error {
-	return upsert(ctx, api, uptimeCheckerAddress, multiAddresses, peerId, walletIndex, NEW_MEMBER_METHOD)
+	client, err := NewActorClientFromIndex(ctx, api, uptimeCheckerAddress, walletIndex)
+	if err != nil {
+		return err
+	}
+	return client.NewMember(ctx, NodeInfoPayload{Id: peerId, Addresses: multiAddresses})
 }
 
 func EditMember(
@@ -498,7 +1192,11 @@ func EditMember(
 	peerId string,
 	walletIndex int,
 ) error {
-	return upsert(ctx, api, uptimeCheckerAddress, multiAddresses, peerId, walletIndex, EDIT_MEMBER_METHOD)
+	client, err := NewActorClientFromIndex(ctx, api, uptimeCheckerAddress, walletIndex)
+	if err != nil {
+		return err
+	}
+	return client.EditMember(ctx, NodeInfoPayload{Id: peerId, Addresses: multiAddresses})
 }
 
 func EditChecker(
@@ -509,7 +1207,11 @@ func EditChecker(
 	peerId string,
 	walletIndex int,
 ) error {
-	return upsert(ctx, api, uptimeCheckerAddress, multiAddresses, peerId, walletIndex, EDIT_CHECKER_METHOD)
+	client, err := NewActorClientFromIndex(ctx, api, uptimeCheckerAddress, walletIndex)
+	if err != nil {
+		return err
+	}
+	return client.EditChecker(ctx, NodeInfoPayload{Id: peerId, Addresses: multiAddresses})
 }
 
 func RmChecker(
@@ -518,107 +1220,22 @@ func RmChecker(
 	uptimeCheckerAddress address.Address,
 	walletIndex int,
 ) error {
-	return remove(ctx, api, uptimeCheckerAddress, walletIndex, RM_CHCKER_METHOD)
-}
-
-func RmMember(
-	ctx context.Context,
-	api v0api.FullNode,
-	uptimeCheckerAddress address.Address,
-	walletIndex int,
-) error {
-	return remove(ctx, api, uptimeCheckerAddress, walletIndex, RM_MEMBER_METHOD)
-}
-
-func upsert(
-	ctx context.Context,
-	api v0api.FullNode,
-	uptimeCheckerAddress address.Address,
-	multiAddresses []string,
-	peerId string,
-	walletIndex int,
-	methodNumber uint32,
-) error {
-	wallet, err := getWalletAddressFromIndex(api, ctx, walletIndex)
-	if err != nil {
-		return err
-	}
-
-	params, err := encodeJson(NodeInfo {
-		Id: peerId,
-		Addresses: multiAddresses,
-	})
+	client, err := NewActorClientFromIndex(ctx, api, uptimeCheckerAddress, walletIndex)
 	if err != nil {
 		return err
 	}
-
-	return executeMsgAndWait(api, uptimeCheckerAddress, ctx, methodNumber, wallet, params)
+	return client.RmChecker(ctx)
 }
 
-func remove(
+func RmMember(
 	ctx context.Context,
 	api v0api.FullNode,
 	uptimeCheckerAddress address.Address,
 	walletIndex int,
-	methodNumber uint32,
 ) error {
-	wallet, err := getWalletAddressFromIndex(api, ctx, walletIndex)
+	client, err := NewActorClientFromIndex(ctx, api, uptimeCheckerAddress, walletIndex)
 	if err != nil {
 		return err
 	}
-	return executeMsgAndWait(api, uptimeCheckerAddress, ctx, methodNumber, wallet, make([]byte, 0))
+	return client.RmMember(ctx)
 }
-
-// executeMsgAndWait executes the method with given params and waits for the message to be executed
-func executeMsgAndWait(
-	api v0api.FullNode,
-	actor address.Address,
-	ctx context.Context,
-	method uint32,
-	from address.Address,
-	params []byte,
-) error {
-	smsg, err := executeMsg(api, actor, ctx, method, from, params)
-	if err != nil {
-		return err
-	}
-
-	log.Infow("waiting for message to execute...")
-	return wait(api, ctx, smsg)
-}
-
-func executeMsg(
-	api v0api.FullNode,
-	actor address.Address,
-	ctx context.Context,
-	method uint32,
-	from address.Address,
-	params []byte,
-) (*chainTypes.SignedMessage, error) {
-	msg := &chainTypes.Message{
-		To:     actor,
-		From:   from,
-		Value:  big.Zero(),
-		Method: abi.MethodNum(method),
-		Params: params,
-	}
-	return api.MpoolPushMessage(ctx, msg, nil)
-}
-
-func wait(
-	api v0api.FullNode,
-	ctx context.Context,
-	smsg *chainTypes.SignedMessage,
-) (error) {
-	wait, err := api.StateWaitMsg(ctx, smsg.Cid(), 0)
-	if err != nil {
-		return err
-	}
-
-	// check it executed successfully
-	if wait.Receipt.ExitCode != 0 {
-		return fmt.Errorf("actor execution failed")
-	}
-
-	return nil
-}
\ No newline at end of file
@@ -0,0 +1,345 @@
+package uptime
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	lapi "github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/v0api"
+	chainTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+)
+
+const testActor = "t01000"
+const testSelf = ActorID(100)
+const testMember = ActorID(200)
+
+const testOnlineAddr = "/ip4/10.0.0.1/tcp/1000"
+const testOfflineAddr = "/ip4/10.0.0.2/tcp/1000"
+
+// newTestChecker returns a checker reading the state from memory, whose tcp probes find only
+// testOnlineAddr online
+func newTestChecker(t *testing.T, source StateSource) *UptimeChecker {
+	u, err := NewUptimeChecker(nil, testActor, nil, testSelf, 0, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.SetStateSource(source)
+	u.RegisterProber("tcp", ProberFunc(func(ctx context.Context, addr MultiAddr) UpInfo {
+		upInfo := newUpInfo()
+		upInfo.isOnline = addr == testOnlineAddr
+		upInfo.checkedTime = uint64(time.Now().Unix())
+		return upInfo
+	}))
+	return &u
+}
+
+func newTestState() *MemoryState {
+	state := NewMemoryState()
+	state.SetChecker(testSelf, NodeInfo{Id: "checker", Creator: testSelf})
+	state.SetMember(testMember, NodeInfo{
+		Id:        "member",
+		Creator:   testMember,
+		Addresses: []MultiAddr{testOnlineAddr, testOfflineAddr},
+	})
+	return state
+}
+
+// waitFor polls the condition until it holds or the timeout expires
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMemberRoundAgainstMemoryState(t *testing.T) {
+	source := NewMemoryStateSource(newTestState())
+	u := newTestChecker(t, source)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := u.watcher.refreshLatest(ctx, chainTypes.EmptyTSK); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		u.monitorMemberNodes(ctx)
+		close(done)
+	}()
+
+	waitFor(t, 5*time.Second, func() bool {
+		member, ok := u.health.Member(testMember)
+		return ok && member.LastChecked != 0
+	})
+
+	member, _ := u.health.Member(testMember)
+	if !member.IsOnline {
+		t.Error("member with an online address is not online")
+	}
+	if !member.Addresses[testOnlineAddr].IsOnline {
+		t.Errorf("%s is not online", testOnlineAddr)
+	}
+	if member.Addresses[testOfflineAddr].IsOnline {
+		t.Errorf("%s is online", testOfflineAddr)
+	}
+
+	// removing the member from the actor drops its health info
+	source.Update(func(state *MemoryState) {
+		state.RemoveMember(testMember)
+	})
+	if err := u.watcher.refreshLatest(ctx, chainTypes.EmptyTSK); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, 5*time.Second, func() bool {
+		_, ok := u.health.Member(testMember)
+		return !ok
+	})
+
+	cancel()
+	<-done
+}
+
+func TestStateSourceLoadsRegistration(t *testing.T) {
+	source := NewMemoryStateSource(NewMemoryState())
+	u := newTestChecker(t, source)
+	ctx := context.Background()
+
+	registered, err := u.HasRegistered(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if registered {
+		t.Fatal("registered in an empty state")
+	}
+
+	source.Update(func(state *MemoryState) {
+		state.SetChecker(testSelf, NodeInfo{Id: "checker", Creator: testSelf})
+	})
+
+	registered, err = u.HasRegistered(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !registered {
+		t.Fatal("not registered once the checker is added")
+	}
+}
+
+// dryRunNode is a node whose simulations of the messages abort with exitCode
+type dryRunNode struct {
+	v0api.FullNode
+
+	exitCode exitcode.ExitCode
+
+	lock  sync.Mutex
+	calls []abi.MethodNum
+}
+
+func (n *dryRunNode) ChainGetTipSetByHeight(ctx context.Context, height abi.ChainEpoch, tsk chainTypes.TipSetKey) (*chainTypes.TipSet, error) {
+	return newTestTipSet(height)
+}
+
+func (n *dryRunNode) StateCall(ctx context.Context, msg *chainTypes.Message, tsk chainTypes.TipSetKey) (*lapi.InvocResult, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.calls = append(n.calls, msg.Method)
+	return &lapi.InvocResult{MsgRct: &chainTypes.MessageReceipt{ExitCode: n.exitCode}}, nil
+}
+
+func newTestTipSet(height abi.ChainEpoch) (*chainTypes.TipSet, error) {
+	miner, err := address.NewIDAddress(1000)
+	if err != nil {
+		return nil, err
+	}
+	c, err := cid.Decode(testMembersCid)
+	if err != nil {
+		return nil, err
+	}
+	return chainTypes.NewTipSet([]*chainTypes.BlockHeader{{
+		Miner:                 miner,
+		Height:                height,
+		Ticket:                &chainTypes.Ticket{VRFProof: []byte{}},
+		ParentStateRoot:       c,
+		ParentMessageReceipts: c,
+		Messages:              c,
+		ParentWeight:          big.Zero(),
+		ParentBaseFee:         big.Zero(),
+	}})
+}
+
+func TestDryRunReportsOnceRegistrationSimulated(t *testing.T) {
+	const reported = ActorID(300)
+
+	node := &dryRunNode{exitCode: EXIT_CODE_NOT_CALLER}
+	u, err := NewUptimeChecker(node, testActor, nil, testSelf, 0, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet, err := address.NewIDAddress(testSelf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.SetWallet(wallet)
+	u.SetDryRun(true)
+
+	// this checker is not registered yet
+	state := NewMemoryState()
+	state.SetChecker(reported, NodeInfo{Id: "reported", Creator: reported})
+	u.SetStateSource(NewMemoryStateSource(state))
+
+	ctx := context.Background()
+	head, err := newTestTipSet(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := u.watcher.refresh(ctx, head); err != nil {
+		t.Fatal(err)
+	}
+
+	down := []UpInfo{newUpInfo()}
+	if err := u.reportIfDown(ctx, reported, &down); err != nil {
+		t.Fatal(err)
+	}
+	if len(node.calls) != 0 {
+		t.Fatalf("report simulated while not registered")
+	}
+
+	u.dryRunRegistered = true
+	if err := u.reportIfDown(ctx, reported, &down); err != nil {
+		t.Fatal(err)
+	}
+	if len(node.calls) != 1 || node.calls[0] != REPORT_CHECKER_METHOD {
+		t.Fatalf("simulated %v, want a report", node.calls)
+	}
+}
+
+func TestHungAddressesRecordedDown(t *testing.T) {
+	u := newTestChecker(t, NewMemoryStateSource(newTestState()))
+	// the probes only end with the deadline of the round
+	u.RegisterProber("tcp", ProberFunc(func(ctx context.Context, addr MultiAddr) UpInfo {
+		<-ctx.Done()
+		upInfo := newUpInfo()
+		upInfo.errReason = PROBE_ERR_CONNECT_TIMEOUT
+		return upInfo
+	}))
+
+	addrs := []MultiAddr{"/ip4/10.0.0.3/tcp/1000", "/ip4/10.0.0.4/tcp/1000", "/ip4/10.0.0.5/tcp/1000"}
+
+	const deadline = 100 * time.Millisecond
+	rctx, cancel := context.WithTimeout(context.Background(), deadline)
+	defer cancel()
+
+	start := time.Now()
+	infos := u.multiAddrsUp(rctx, &addrs)
+	if elapsed := time.Since(start); elapsed > 2*deadline {
+		t.Errorf("addresses probed in %s, not concurrently", elapsed)
+	}
+	if len(infos) != len(addrs) {
+		t.Fatalf("%d results, want %d", len(infos), len(addrs))
+	}
+
+	if err := u.recordMemberHealthInfo(context.Background(), testMember, &infos); err != nil {
+		t.Fatal(err)
+	}
+	member, ok := u.health.Member(testMember)
+	if !ok {
+		t.Fatal("member not recorded")
+	}
+	if member.IsOnline {
+		t.Error("member with hung addresses is online")
+	}
+	for _, addr := range addrs {
+		info, ok := member.Addresses[addr]
+		if !ok || info.IsOnline {
+			t.Errorf("%s not recorded down", addr)
+		}
+	}
+}
+
+func TestMemberSharingCheckerAddress(t *testing.T) {
+	u := newTestChecker(t, NewMemoryStateSource(newTestState()))
+	const shared = "/ip4/10.0.0.9/tcp/1000"
+	u.checkerAddresses = []MultiAddr{shared}
+
+	// the shared address comes first, so the probes are not aligned with the addresses
+	addrs := []MultiAddr{shared, testOfflineAddr, testOnlineAddr}
+	infos := u.multiAddrsUp(context.Background(), &addrs)
+	if len(infos) != 2 {
+		t.Fatalf("%d results, want 2", len(infos))
+	}
+
+	if err := u.recordMemberHealthInfo(context.Background(), testMember, &infos); err != nil {
+		t.Fatal(err)
+	}
+	member, ok := u.health.Member(testMember)
+	if !ok {
+		t.Fatal("member not recorded")
+	}
+	if !member.IsOnline {
+		t.Error("member is offline")
+	}
+	if _, ok := member.Addresses[shared]; ok {
+		t.Errorf("address %s of the checker recorded", shared)
+	}
+	if info := member.Addresses[testOnlineAddr]; !info.IsOnline {
+		t.Errorf("%s recorded down", testOnlineAddr)
+	}
+	if info, ok := member.Addresses[testOfflineAddr]; !ok || info.IsOnline {
+		t.Errorf("%s not recorded down", testOfflineAddr)
+	}
+}
+
+func TestStartWithoutNode(t *testing.T) {
+	const newMember = ActorID(201)
+	const offlineChecker = ActorID(300)
+
+	state := newTestState()
+	state.SetChecker(offlineChecker, NodeInfo{Id: "offline", Creator: offlineChecker, Addresses: []MultiAddr{testOfflineAddr}})
+	source := NewMemoryStateSource(state)
+	u := newTestChecker(t, source)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := u.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer u.Stop()
+
+	waitFor(t, 5*time.Second, func() bool {
+		member, ok := u.health.Member(testMember)
+		return ok && member.IsOnline
+	})
+
+	// the updates of the state drive the monitors as the head changes of a node
+	source.Update(func(state *MemoryState) {
+		state.SetMember(newMember, NodeInfo{Id: "new", Creator: newMember, Addresses: []MultiAddr{testOfflineAddr}})
+	})
+	waitFor(t, 5*time.Second, func() bool {
+		member, ok := u.health.Member(newMember)
+		return ok && member.LastChecked != 0 && !member.IsOnline
+	})
+
+	// the memory state is the same at every tipset, the confirmed one included
+	waitFor(t, 5*time.Second, func() bool {
+		confirmed, ts := u.watcher.Confirmed()
+		if confirmed == nil || ts == nil {
+			return false
+		}
+		ids, err := confirmed.ListMembers()
+		return err == nil && len(ids) == 2
+	})
+}
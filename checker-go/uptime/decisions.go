@@ -0,0 +1,52 @@
+package uptime
+
+import (
+	"sync"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+const REORG_BUFFER = 16 // reorgs buffered before the re-evaluation falls behind
+
+// pendingDecisions holds the report decisions taken on the confirmed state that could still
+// be undone by a reorg, i.e. whose tipset is less than confidence epochs below the confirmed one
+type pendingDecisions struct {
+	heights map[ActorID]abi.ChainEpoch
+
+	lock sync.Mutex
+}
+
+func newPendingDecisions() *pendingDecisions {
+	return &pendingDecisions{
+		heights: make(map[ActorID]abi.ChainEpoch),
+	}
+}
+
+// add records the decision on the checker taken at the confirmed height and drops the
+// decisions that are final by now
+func (p *pendingDecisions) add(checker ActorID, height abi.ChainEpoch, confidence abi.ChainEpoch) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.heights[checker] = height
+	for actorID, h := range p.heights {
+		if h+confidence <= height {
+			delete(p.heights, actorID)
+		}
+	}
+}
+
+// takeReverted removes and returns the checkers decided at or above the reverted height
+func (p *pendingDecisions) takeReverted(reverted abi.ChainEpoch) []ActorID {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	checkers := make([]ActorID, 0)
+	for actorID, h := range p.heights {
+		if h >= reverted {
+			checkers = append(checkers, actorID)
+			delete(p.heights, actorID)
+		}
+	}
+	return checkers
+}
@@ -0,0 +1,231 @@
+package uptime
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/filecoin-project/go-address"
+	hamt "github.com/filecoin-project/go-hamt-ipld/v3"
+	"github.com/filecoin-project/lotus/api/v0api"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/v7/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v7/actors/util/adt"
+	"github.com/ipfs/go-cid"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+// Types of the changes of the members and checkers
+const CHANGE_ADDED = "added"
+const CHANGE_EDITED = "edited"
+const CHANGE_REMOVED = "removed"
+
+// Types of the changes of the votes on the offline checkers
+const VOTES_ADDED = "votes_added"     // the checker got reported, or new votes were cast
+const VOTES_RESET = "votes_reset"     // a new round of votes was started
+const VOTES_REMOVED = "votes_removed" // the checker is no longer reported, e.g. it got evicted
+
+// NodeChange is the change of a member or checker. Before is nil for added nodes and
+// After for removed ones.
+type NodeChange struct {
+	Actor  ActorID   `json:"actor"`
+	Type   string    `json:"type"`
+	Before *NodeInfo `json:"before"`
+	After  *NodeInfo `json:"after"`
+}
+
+// VotesChange is the change of the votes on a reported checker. NewVoters are the voters in
+// After that were not in Before.
+type VotesChange struct {
+	Checker   ActorID   `json:"checker"`
+	Type      string    `json:"type"`
+	Before    *Votes    `json:"before"`
+	After     *Votes    `json:"after"`
+	NewVoters []ActorID `json:"newVoters"`
+}
+
+// StateDiff holds the changes of the actor state between two roots, sorted by actor id
+type StateDiff struct {
+	Members         []NodeChange  `json:"members"`
+	Checkers        []NodeChange  `json:"checkers"`
+	OfflineCheckers []VotesChange `json:"offlineCheckers"`
+}
+
+func (d *StateDiff) IsEmpty() bool {
+	return len(d.Members) == 0 && len(d.Checkers) == 0 && len(d.OfflineCheckers) == 0
+}
+
+// Diff returns the changes that transform the prev state into this one. Only the parts of
+// the HAMTs that differ are walked.
+func (m *HAMTState) Diff(other StateReader) (*StateDiff, error) {
+	prev, ok := other.(*HAMTState)
+	if !ok {
+		return nil, fmt.Errorf("cannot diff %T against a HAMT state", other)
+	}
+
+	members, err := diffNodes(prev.store, m.store, prev.inner.Members, m.inner.Members)
+	if err != nil {
+		return nil, err
+	}
+
+	checkers, err := diffNodes(prev.store, m.store, prev.inner.Checkers, m.inner.Checkers)
+	if err != nil {
+		return nil, err
+	}
+
+	offline, err := diffVotes(prev.store, m.store, prev.inner.OfflineCheckers, m.inner.OfflineCheckers)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StateDiff{
+		Members:         members,
+		Checkers:        checkers,
+		OfflineCheckers: offline,
+	}, nil
+}
+
+// DiffAt returns the changes of the actor state between the two tipsets
+func DiffAt(ctx context.Context, api v0api.FullNode, addr address.Address, from types.TipSetKey, to types.TipSetKey) (*StateDiff, error) {
+	prev, err := LoadHAMTStateAt(ctx, api, addr, from)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := LoadHAMTStateAt(ctx, api, addr, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return cur.Diff(&prev)
+}
+
+func diffNodes(prevStore adt.Store, curStore adt.Store, prev cid.Cid, cur cid.Cid) ([]NodeChange, error) {
+	nodeChanges := make([]NodeChange, 0)
+
+	changes, err := diffHAMT(prevStore, curStore, prev, cur)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range changes {
+		actorID, err := parseActorIDFromString(c.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		change := NodeChange{Actor: actorID}
+		if change.Before, err = decodeNodeInfo(c.Before); err != nil {
+			return nil, err
+		}
+		if change.After, err = decodeNodeInfo(c.After); err != nil {
+			return nil, err
+		}
+
+		switch c.Type {
+		case hamt.Add:
+			change.Type = CHANGE_ADDED
+		case hamt.Remove:
+			change.Type = CHANGE_REMOVED
+		case hamt.Modify:
+			change.Type = CHANGE_EDITED
+		}
+		nodeChanges = append(nodeChanges, change)
+	}
+
+	sort.Slice(nodeChanges, func(i, j int) bool { return nodeChanges[i].Actor < nodeChanges[j].Actor })
+	return nodeChanges, nil
+}
+
+func diffVotes(prevStore adt.Store, curStore adt.Store, prev cid.Cid, cur cid.Cid) ([]VotesChange, error) {
+	votesChanges := make([]VotesChange, 0)
+
+	changes, err := diffHAMT(prevStore, curStore, prev, cur)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range changes {
+		actorID, err := parseActorIDFromString(c.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		before, err := decodeVotes(c.Before)
+		if err != nil {
+			return nil, err
+		}
+		after, err := decodeVotes(c.After)
+		if err != nil {
+			return nil, err
+		}
+
+		votesChanges = append(votesChanges, newVotesChange(actorID, before, after))
+	}
+
+	sort.Slice(votesChanges, func(i, j int) bool { return votesChanges[i].Checker < votesChanges[j].Checker })
+	return votesChanges, nil
+}
+
+// newVotesChange classifies the change of the votes on the checker, before or after being nil
+// when the checker was not reported
+func newVotesChange(checker ActorID, before *Votes, after *Votes) VotesChange {
+	change := VotesChange{Checker: checker, Before: before, After: after, NewVoters: make([]ActorID, 0)}
+	if after == nil {
+		change.Type = VOTES_REMOVED
+		return change
+	}
+
+	change.Type = VOTES_ADDED
+	for _, voter := range after.Votes {
+		if before == nil || !containsActor(before.Votes, voter) {
+			change.NewVoters = append(change.NewVoters, voter)
+		}
+	}
+
+	// the actor only sets LastVote when it starts a new round of votes
+	if before != nil && after.LastVote != before.LastVote {
+		change.Type = VOTES_RESET
+	}
+	return change
+}
+
+func diffHAMT(prevStore adt.Store, curStore adt.Store, prev cid.Cid, cur cid.Cid) ([]*hamt.Change, error) {
+	options := make([]hamt.Option, 0, len(adt.DefaultHamtOptions)+1)
+	options = append(options, adt.DefaultHamtOptions...)
+	options = append(options, hamt.UseTreeBitWidth(builtin.DefaultHamtBitwidth))
+
+	return hamt.Diff(curStore.Context(), prevStore, curStore, prev, cur, options...)
+}
+
+func decodeNodeInfo(d *cbg.Deferred) (*NodeInfo, error) {
+	if d == nil {
+		return nil, nil
+	}
+	info := NodeInfo{}
+	if err := info.UnmarshalCBOR(bytes.NewReader(d.Raw)); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func decodeVotes(d *cbg.Deferred) (*Votes, error) {
+	if d == nil {
+		return nil, nil
+	}
+	votes := Votes{}
+	if err := votes.UnmarshalCBOR(bytes.NewReader(d.Raw)); err != nil {
+		return nil, err
+	}
+	return &votes, nil
+}
+
+func containsActor(actors []ActorID, actor ActorID) bool {
+	for _, a := range actors {
+		if a == actor {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package uptime
+
+import (
+	"sync"
+	"time"
+)
+
+const DEFAULT_EVENT_HISTORY = 1024 // number of latest events kept to resume streams
+
+// Types of the events published by the monitor loops
+const EVENT_MEMBER_UP = "member_up"
+const EVENT_MEMBER_DOWN = "member_down"
+const EVENT_LATENCY_HIGH = "latency_high"     // the latency of an address went above the threshold
+const EVENT_LATENCY_NORMAL = "latency_normal" // the latency of an address went back below the threshold
+const EVENT_CHECKER_REPORTED = "checker_reported"
+const EVENT_CHECKER_REMOVED = "checker_removed"
+
+// EVENT_RESYNC is sent to the clients resuming from an event no longer kept, they should
+// reload the full state. It is never published on the bus.
+const EVENT_RESYNC = "resync"
+
+// Event is a change of the checked nodes. Seq is increasing and starts at 1.
+type Event struct {
+	Seq       uint64    `json:"seq"`
+	Type      string    `json:"type"`
+	Timestamp int64     `json:"timestamp"`
+	Actor     ActorID   `json:"actor"`
+	Addr      MultiAddr `json:"addr,omitempty"`
+	Latency   uint64    `json:"latency,omitempty"`
+}
+
+// EventBus fans the events out to the subscribers and keeps the latest ones, so that
+// clients can resume from the last sequence number they have seen
+type EventBus struct {
+	history []Event // ring buffer of the latest events
+	next    int
+	size    int
+	seq     uint64
+
+	subscribers    map[uint64]chan Event
+	nextSubscriber uint64
+
+	rwLock sync.RWMutex
+}
+
+func NewEventBus(history int) *EventBus {
+	return &EventBus{
+		history:     make([]Event, history),
+		subscribers: make(map[uint64]chan Event),
+	}
+}
+
+// Publish assigns the next sequence number to the event and sends it to the subscribers.
+// Subscribers that do not keep up are dropped, they are expected to resume from the last
+// event they received.
+func (b *EventBus) Publish(e Event) Event {
+	b.rwLock.Lock()
+	defer b.rwLock.Unlock()
+
+	b.seq++
+	e.Seq = b.seq
+	if e.Timestamp == 0 {
+		e.Timestamp = time.Now().Unix()
+	}
+
+	b.history[b.next] = e
+	b.next = (b.next + 1) % len(b.history)
+	if b.size < len(b.history) {
+		b.size++
+	}
+
+	for id, ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+			log.Warnw("event subscriber is lagging behind, dropping it", "subscriber", id)
+			delete(b.subscribers, id)
+			close(ch)
+		}
+	}
+
+	return e
+}
+
+// Seq returns the sequence number of the last published event
+func (b *EventBus) Seq() uint64 {
+	b.rwLock.RLock()
+	defer b.rwLock.RUnlock()
+	return b.seq
+}
+
+// Subscribe returns the kept events published after since, the channel of the next events
+// and the function to cancel the subscription. The returned bool is false if some events
+// after since are no longer kept. The channel is closed if the subscriber lags behind.
+func (b *EventBus) Subscribe(since uint64, buffer int) ([]Event, <-chan Event, func(), bool) {
+	b.rwLock.Lock()
+	defer b.rwLock.Unlock()
+
+	backlog, complete := b.since(since)
+
+	id := b.nextSubscriber
+	b.nextSubscriber++
+
+	ch := make(chan Event, buffer)
+	b.subscribers[id] = ch
+
+	cancel := func() {
+		b.rwLock.Lock()
+		defer b.rwLock.Unlock()
+		if _, ok := b.subscribers[id]; ok {
+			delete(b.subscribers, id)
+			close(ch)
+		}
+	}
+	return backlog, ch, cancel, complete
+}
+
+// since must be called with the lock held. A seq ahead of the bus was seen before a restart
+// of the checker, the events since the restart cannot be told apart from the ones already
+// seen, so the client must resync.
+func (b *EventBus) since(seq uint64) ([]Event, bool) {
+	events := make([]Event, 0)
+	if seq >= b.seq {
+		return events, seq == b.seq
+	}
+
+	oldest := b.seq - uint64(b.size) + 1
+	complete := seq+1 >= oldest
+
+	start := (b.next - b.size + len(b.history)) % len(b.history)
+	for i := 0; i < b.size; i++ {
+		e := b.history[(start+i)%len(b.history)]
+		if e.Seq > seq {
+			events = append(events, e)
+		}
+	}
+	return events, complete
+}
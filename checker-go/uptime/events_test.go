@@ -0,0 +1,57 @@
+package uptime
+
+import (
+	"testing"
+)
+
+func TestEventBusResume(t *testing.T) {
+	b := NewEventBus(2)
+	for i := 0; i < 3; i++ {
+		b.Publish(Event{Type: EVENT_MEMBER_UP, Actor: testMember})
+	}
+
+	cases := []struct {
+		name     string
+		since    uint64
+		events   int
+		complete bool
+	}{
+		{"up to date", 3, 0, true},
+		{"kept events", 1, 2, true},
+		{"events no longer kept", 0, 2, false},
+		// e.g. seen before the checker restarted
+		{"ahead of the bus", 10, 0, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			backlog, _, cancel, complete := b.Subscribe(c.since, 1)
+			defer cancel()
+
+			if len(backlog) != c.events {
+				t.Errorf("%d events, want %d", len(backlog), c.events)
+			}
+			if complete != c.complete {
+				t.Errorf("complete %v, want %v", complete, c.complete)
+			}
+		})
+	}
+}
+
+func TestEventBusResumeAfterRestart(t *testing.T) {
+	before := NewEventBus(DEFAULT_EVENT_HISTORY)
+	for i := 0; i < 5; i++ {
+		before.Publish(Event{Type: EVENT_MEMBER_UP, Actor: testMember})
+	}
+	seen := before.Seq()
+
+	// the bus of the restarted checker starts over
+	after := NewEventBus(DEFAULT_EVENT_HISTORY)
+	after.Publish(Event{Type: EVENT_MEMBER_DOWN, Actor: testMember})
+
+	_, _, cancel, complete := after.Subscribe(seen, 1)
+	defer cancel()
+	if complete {
+		t.Error("resumed from an event of the previous run without resync")
+	}
+}
@@ -10,7 +10,6 @@ func main() {
 	if err := gen.WriteMapEncodersToFile("../cbor_gen.go", "uptime",
 		uptime.NodeInfo{},
 		uptime.Votes{},
-		uptime.HAMTStateInner{},
 	); err != nil {
 		panic(err)
 	}
@@ -15,11 +15,16 @@ import (
 	cbor "github.com/ipfs/go-ipld-cbor"
 )
 
+// HAMTStateInner is the state of the actor, see state_decoder.go for its decoding
 type HAMTStateInner struct {
     Members cid.Cid
     Checkers cid.Cid
     OfflineCheckers cid.Cid
     TotalCheckers uint64
+    VotingDuration ChainEpoch
+
+    // Version of the state layout, not part of the state
+    Version uint64
 }
 
 type HAMTState struct {
@@ -28,17 +33,28 @@ type HAMTState struct {
 }
 
 func LoadHAMTState(ctx context.Context, api v0api.FullNode, addr address.Address) (HAMTState, error)  {
-	act, err := api.StateGetActor(ctx, addr, types.EmptyTSK)
+	return LoadHAMTStateAt(ctx, api, addr, types.EmptyTSK)
+}
+
+// LoadHAMTStateAt loads the state of the actor at the tipset, EmptyTSK being the head
+func LoadHAMTStateAt(ctx context.Context, api v0api.FullNode, addr address.Address, tsk types.TipSetKey) (HAMTState, error)  {
+	act, err := api.StateGetActor(ctx, addr, tsk)
 	if err != nil {
 		return HAMTState{}, err
 	}
 
+	return LoadHAMTStateFromHead(ctx, api, act.Head)
+}
+
+// LoadHAMTStateFromHead decodes the state of the actor from its head cid
+func LoadHAMTStateFromHead(ctx context.Context, api v0api.FullNode, head cid.Cid) (HAMTState, error) {
 	var st HAMTStateInner
 	bs := blockstore.NewAPIBlockstore(api)
 	cst := cbor.NewCborStore(bs)
-	if err := cst.Get(ctx, act.Head, &st); err != nil {
+	if err := cst.Get(ctx, head, &st); err != nil {
 		return HAMTState{}, err
 	}
+	log.Debugw("actor state decoded", "head", head, "version", st.Version)
 
 	return HAMTState {
 		inner: st,
@@ -69,23 +85,68 @@ func (m *HAMTState) GetOfflineCheckers() ([]ActorID, error) {
 }
 
 func (m *HAMTState) HasVotedForReportedChecker(reported ActorID, voter ActorID) (bool, error) {
-	ccid := m.inner.OfflineCheckers
-	checkerMap, err := adt.AsMap(m.store, ccid, builtin.DefaultHamtBitwidth)
-	if err != nil {
+	v, err := m.GetVotes(reported)
+	if err != nil || v == nil {
 		return false, err
 	}
 
+	return v.HasVoted(voter)
+}
+
+// GetVotes returns the votes on the reported checker, nil if not reported
+func (m *HAMTState) GetVotes(reported ActorID) (*Votes, error) {
+	checkerMap, err := adt.AsMap(m.store, m.inner.OfflineCheckers, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, err
+	}
+
 	v := Votes{}
 	found, err := checkerMap.Get(NewWrappedActorKey(reported), &v)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
 	if !found {
-		return false, nil
+		return nil, nil
 	}
 
-	return v.HasVoted(voter)
+	return &v, nil
+}
+
+// ListVotes returns the votes on all the reported checkers
+func (m *HAMTState) ListVotes() (map[ActorID]*Votes, error) {
+	votes := make(map[ActorID]*Votes)
+
+	checkerMap, err := adt.AsMap(m.store, m.inner.OfflineCheckers, builtin.DefaultHamtBitwidth)
+	if err != nil {
+		return nil, err
+	}
+
+	v := Votes{}
+	err = checkerMap.ForEach(&v, func(key string) error {
+		actorID, err := parseActorIDFromString(key)
+		if err != nil {
+			return err
+		}
+		copied := Votes{LastVote: v.LastVote, Votes: append([]ActorID{}, v.Votes...)}
+		votes[actorID] = &copied
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return votes, nil
+}
+
+// TotalCheckers returns the number of checkers the eviction threshold is computed against
+func (m *HAMTState) TotalCheckers() uint64 {
+	return m.inner.TotalCheckers
+}
+
+// VotingDuration returns the voting duration of the actor, in epochs
+func (m *HAMTState) VotingDuration() ChainEpoch {
+	return m.inner.VotingDuration
 }
 
 func (m *HAMTState) HasRegistered(actor ActorID) (bool, error) {
@@ -98,33 +159,41 @@ func (m *HAMTState) HasRegistered(actor ActorID) (bool, error) {
 }
 
 func (m *HAMTState) ListCheckerMultiAddrs(actorID ActorID) (*[]MultiAddr, error) {
-	checkerMap, err := adt.AsMap(m.store, m.inner.Checkers, builtin.DefaultHamtBitwidth)
-	if err != nil {
+	d, err := m.GetCheckerInfo(actorID)
+	if err != nil || d == nil {
 		return nil, err
 	}
 
-	d := NodeInfo{}
-	found, err := checkerMap.Get(NewWrappedActorKey(actorID), &d)
-	if err != nil {
+	log.Debugw("addresses", "actorID", actorID, "addresses", d)
+	return &d.Addresses, nil
+}
+
+func (m *HAMTState) ListMemberMultiAddrs(actorID ActorID) (*[]MultiAddr, error) {
+	d, err := m.GetMemberInfo(actorID)
+	if err != nil || d == nil {
 		return nil, err
 	}
+	return &d.Addresses, nil
+}
 
-	if !found {
-		return nil, nil
-	}
+// GetCheckerInfo returns the node info of the checker, nil if not registered
+func (m *HAMTState) GetCheckerInfo(actorID ActorID) (*NodeInfo, error) {
+	return m.getNodeInfo(m.inner.Checkers, actorID)
+}
 
-	log.Debugw("addresses", "actorID", actorID, "addresses", d)
-	return &d.Addresses, nil
+// GetMemberInfo returns the node info of the member, nil if not registered
+func (m *HAMTState) GetMemberInfo(actorID ActorID) (*NodeInfo, error) {
+	return m.getNodeInfo(m.inner.Members, actorID)
 }
 
-func (m *HAMTState) ListMemberMultiAddrs(actorID ActorID) (*[]MultiAddr, error) {
-	checkerMap, err := adt.AsMap(m.store, m.inner.Members, builtin.DefaultHamtBitwidth)
+func (m *HAMTState) getNodeInfo(ccid cid.Cid, actorID ActorID) (*NodeInfo, error) {
+	nodeMap, err := adt.AsMap(m.store, ccid, builtin.DefaultHamtBitwidth)
 	if err != nil {
 		return nil, err
 	}
 
 	d := NodeInfo{}
-	found, err := checkerMap.Get(NewWrappedActorKey(actorID), &d)
+	found, err := nodeMap.Get(NewWrappedActorKey(actorID), &d)
 	if err != nil {
 		return nil, err
 	}
@@ -133,7 +202,7 @@ func (m *HAMTState) ListMemberMultiAddrs(actorID ActorID) (*[]MultiAddr, error)
 		return nil, nil
 	}
 
-	return &d.Addresses, nil
+	return &d, nil
 }
 
 func (m *HAMTState) ListMembers() ([]ActorID, error) {
@@ -0,0 +1,79 @@
+package uptime
+
+import (
+	"sync"
+)
+
+// HealthRegistry holds the health information of the member nodes. It is safe for concurrent
+// use: writers update members atomically and readers only ever get copies, so the probing
+// rounds and the http server never share mutable state. Changes of the members are streamed
+// to the clients by the EventBus, which the monitors publish to after updating the registry.
+type HealthRegistry struct {
+	members map[ActorID]MemberHealthInfo
+
+	rwLock sync.RWMutex
+}
+
+func NewHealthRegistry() *HealthRegistry {
+	return &HealthRegistry{
+		members: make(map[ActorID]MemberHealthInfo),
+	}
+}
+
+// Update applies fn to the member, creating it if missing
+func (r *HealthRegistry) Update(actorID ActorID, fn func(member *MemberHealthInfo)) {
+	r.rwLock.Lock()
+	defer r.rwLock.Unlock()
+
+	member, ok := r.members[actorID]
+	if !ok {
+		member = newMemberHealthInfo()
+	}
+
+	fn(&member)
+	r.members[actorID] = member
+}
+
+// Remove drops the member, e.g. once it is removed from the actor
+func (r *HealthRegistry) Remove(actorID ActorID) {
+	r.rwLock.Lock()
+	defer r.rwLock.Unlock()
+	delete(r.members, actorID)
+}
+
+// Member returns a copy of the health information of the member
+func (r *HealthRegistry) Member(actorID ActorID) (MemberHealthInfo, bool) {
+	r.rwLock.RLock()
+	defer r.rwLock.RUnlock()
+
+	member, ok := r.members[actorID]
+	if !ok {
+		return MemberHealthInfo{}, false
+	}
+	return member.copy(), true
+}
+
+// Snapshot returns a copy of the health information of all the members
+func (r *HealthRegistry) Snapshot() map[ActorID]MemberHealthInfo {
+	r.rwLock.RLock()
+	defer r.rwLock.RUnlock()
+
+	data := make(map[ActorID]MemberHealthInfo, len(r.members))
+	for actorID, member := range r.members {
+		data[actorID] = member.copy()
+	}
+	return data
+}
+
+// copy returns a deep copy of the member without the internal trackers
+func (m *MemberHealthInfo) copy() MemberHealthInfo {
+	c := *m
+	c.availability = nil
+	c.Addresses = make(map[MultiAddr]HealtcheckInfo, len(m.Addresses))
+	for addr, info := range m.Addresses {
+		info.window = nil
+		info.availability = nil
+		c.Addresses[addr] = info
+	}
+	return c
+}
@@ -0,0 +1,151 @@
+package uptime
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	chainTypes "github.com/filecoin-project/lotus/chain/types"
+)
+
+const testWriters = 4
+const testUpdates = 200
+
+// updateMember records a probe of the member, as the member monitor does
+func updateMember(r *HealthRegistry, actorID ActorID, online bool) {
+	r.Update(actorID, func(member *MemberHealthInfo) {
+		now := uint64(time.Now().Unix())
+		info, ok := member.Addresses[testOnlineAddr]
+		if !ok {
+			info = newHealtcheckInfo(testOnlineAddr)
+		}
+		info.IsOnline = online
+		info.LastChecked = now
+		info.availability.add(now, online)
+		member.Addresses[testOnlineAddr] = info
+		member.IsOnline = online
+		member.LastChecked = now
+		member.availability.add(now, online)
+	})
+}
+
+func TestHealthRegistryConcurrentUpdates(t *testing.T) {
+	r := NewHealthRegistry()
+
+	var writers sync.WaitGroup
+	for w := 0; w < testWriters; w++ {
+		writers.Add(1)
+		go func(w int) {
+			defer writers.Done()
+			for i := 0; i < testUpdates; i++ {
+				updateMember(r, ActorID(i%10), (i+w)%2 == 0)
+			}
+		}(w)
+	}
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			for actorID, member := range r.Snapshot() {
+				// the copies are owned by the reader
+				member.Addresses["mutated"] = HealtcheckInfo{}
+				if _, ok := r.Member(actorID); !ok {
+					t.Errorf("member %d in the snapshot is missing", actorID)
+				}
+			}
+		}
+	}()
+
+	writers.Wait()
+	close(stop)
+	readers.Wait()
+
+	snapshot := r.Snapshot()
+	if len(snapshot) != 10 {
+		t.Errorf("got %d members, want 10", len(snapshot))
+	}
+	for actorID, member := range snapshot {
+		if member.IsOnline != member.Addresses[testOnlineAddr].IsOnline {
+			t.Errorf("member %d is not consistent with its address", actorID)
+		}
+		if _, ok := member.Addresses["mutated"]; ok {
+			t.Errorf("snapshot of member %d was changed by a reader", actorID)
+		}
+	}
+}
+
+func TestHealthRegistryConcurrentHTTPReads(t *testing.T) {
+	state := newTestState()
+	u := newTestChecker(t, NewMemoryStateSource(state))
+	if err := u.watcher.refreshLatest(context.Background(), chainTypes.EmptyTSK); err != nil {
+		t.Fatal(err)
+	}
+
+	// the routes of the node info server of run
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		info, err := u.NodeInfoJsonString()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, info)
+	})
+	mux.Handle("/v1/", NewAPIHandler(u))
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	stop := make(chan struct{})
+	var writers sync.WaitGroup
+	writers.Add(1)
+	go func() {
+		defer writers.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			updateMember(u.health, testMember, i%2 == 0)
+		}
+	}()
+
+	var readers sync.WaitGroup
+	for _, path := range []string{"/", "/v1/members", fmt.Sprintf("/v1/members/%d", testMember)} {
+		readers.Add(1)
+		go func(path string) {
+			defer readers.Done()
+			for i := 0; i < 50; i++ {
+				res, err := http.Get(server.URL + path)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				io.Copy(io.Discard, res.Body)
+				res.Body.Close()
+				if res.StatusCode != http.StatusOK {
+					t.Errorf("GET %s: status %d", path, res.StatusCode)
+					return
+				}
+			}
+		}(path)
+	}
+
+	readers.Wait()
+	close(stop)
+	writers.Wait()
+}
@@ -0,0 +1,159 @@
+package uptime
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	libp2pMultiaddr "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+const HTTP_HEALTHCHECK = "http"
+const PING_HEALTHCHECK = "ping"
+
+const DEFAULT_HTTP_METHOD = http.MethodGet
+
+// healthcheck is the liveness check advertised at the tail of a node's multiaddr,
+// i.e. `/ping` for the libp2p ping protocol or `/http/<method>/<path>` for an HTTP query.
+type healthcheck struct {
+	protocol string
+	method   string
+	path     string
+}
+
+// splitHealthcheckAddr separates the dialable part of the multiaddr from its healthcheck
+// suffix. The protocol of the healthcheck is left empty if the address has no suffix.
+//
+// e.g. /ip4/10.1.1.1/tcp/8081/http/get/healtcheck => /ip4/10.1.1.1/tcp/8081, GET /healtcheck
+func splitHealthcheckAddr(addr MultiAddr) (libp2pMultiaddr.Multiaddr, healthcheck, error) {
+	hc := healthcheck{}
+
+	parts := strings.Split(strings.TrimRight(addr, "/"), "/")
+	if len(parts) < 2 || parts[0] != "" {
+		return nil, hc, fmt.Errorf("invalid multiaddr %s", addr)
+	}
+	parts = parts[1:]
+
+	end := len(parts)
+	for i := 0; i < len(parts); {
+		name := parts[i]
+
+		if name == HTTP_HEALTHCHECK {
+			hc.protocol = HTTP_HEALTHCHECK
+			hc.method = DEFAULT_HTTP_METHOD
+			hc.path = "/"
+			if i+1 < len(parts) {
+				hc.method = strings.ToUpper(parts[i+1])
+			}
+			if i+2 < len(parts) {
+				hc.path = "/" + strings.Join(parts[i+2:], "/")
+			}
+			end = i
+			break
+		}
+
+		if name == PING_HEALTHCHECK && i == len(parts)-1 {
+			hc.protocol = PING_HEALTHCHECK
+			end = i
+			break
+		}
+
+		p := libp2pMultiaddr.ProtocolWithName(name)
+		if p.Code == 0 {
+			return nil, hc, fmt.Errorf("unknown protocol %s in multiaddr %s", name, addr)
+		}
+
+		switch {
+		case p.Path:
+			i = len(parts)
+		case p.Size == 0:
+			i++
+		default:
+			i += 2
+		}
+	}
+
+	base, err := libp2pMultiaddr.NewMultiaddr("/" + strings.Join(parts[:end], "/"))
+	if err != nil {
+		return nil, hc, err
+	}
+	return base, hc, nil
+}
+
+// HTTPProber queries the `/http/<method>/<path>` healthcheck endpoint of the node.
+// Any 2xx status is considered online.
+type HTTPProber struct {
+	client *http.Client
+}
+
+func NewHTTPProber(client *http.Client) *HTTPProber {
+	return &HTTPProber{client: client}
+}
+
+func (p *HTTPProber) Probe(ctx context.Context, addrStr MultiAddr) UpInfo {
+	upInfo := newUpInfo()
+
+	addr, hc, err := splitHealthcheckAddr(addrStr)
+	if err != nil {
+		log.Errorw("cannot parse multi addr", "addr", addrStr, "err", err)
+		upInfo.errReason = PROBE_ERR_PARSE
+		return upInfo
+	}
+
+	if hc.protocol != HTTP_HEALTHCHECK {
+		log.Errorw("multi addr has no http healthcheck", "addr", addrStr)
+		upInfo.errReason = PROBE_ERR_PARSE
+		return upInfo
+	}
+
+	_, hostPort, err := manet.DialArgs(addr)
+	if err != nil {
+		log.Errorw("cannot convert multi addr to http host", "addr", addr, "err", err)
+		upInfo.errReason = PROBE_ERR_PARSE
+		return upInfo
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, PING_TIMEOUT)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(cctx, hc.method, "http://"+hostPort+hc.path, nil)
+	if err != nil {
+		log.Errorw("cannot create http healthcheck request", "addr", addr, "err", err)
+		upInfo.errReason = PROBE_ERR_PARSE
+		return upInfo
+	}
+
+	now := time.Now()
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		log.Errorw("cannot query http healthcheck", "addr", addr, "err", err)
+		upInfo.errReason = failureReason(cctx, PROBE_ERR_HTTP, PROBE_ERR_HTTP_TIMEOUT)
+		return upInfo
+	}
+	defer resp.Body.Close()
+
+	size, err := io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		log.Errorw("cannot read http healthcheck body", "addr", addr, "err", err)
+		upInfo.errReason = failureReason(cctx, PROBE_ERR_HTTP, PROBE_ERR_HTTP_TIMEOUT)
+		return upInfo
+	}
+
+	log.Debugw("got http healthcheck response", "addr", addr, "status", resp.StatusCode, "size", size)
+
+	upInfo.isOnline = resp.StatusCode >= 200 && resp.StatusCode < 300
+	upInfo.checkedTime = uint64(time.Now().Unix())
+	upInfo.latency = uint64(time.Since(now))
+	upInfo.statusCode = resp.StatusCode
+	upInfo.bodySize = uint64(size)
+	if !upInfo.isOnline {
+		upInfo.errReason = PROBE_ERR_HTTP_STATUS
+	}
+
+	return upInfo
+}
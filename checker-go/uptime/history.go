@@ -0,0 +1,323 @@
+package uptime
+
+import (
+	"context"
+	"encoding/base32"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	levelds "github.com/ipfs/go-ds-leveldb"
+)
+
+const DEFAULT_HISTORY_RETENTION = UPTIME_WINDOW_MONTH   // aggregates older than this are deleted
+const DEFAULT_HISTORY_RAW_RETENTION = UPTIME_WINDOW_DAY // raw probes older than this are downsampled
+const HISTORY_AGGREGATE_INTERVAL = time.Hour           // interval covered by a downsampled aggregate
+const HISTORY_COMPACT_INTERVAL = 10 * time.Minute      // how often the store is downsampled and pruned
+
+const PROBES_PREFIX = "/probes"
+const AGGREGATES_PREFIX = "/aggregates"
+
+// MEMBER_RECORD_ADDR is the address of the records holding the member level result of a round
+const MEMBER_RECORD_ADDR = ""
+
+// ProbeRecord is the result of a single probe of a member multiaddr
+type ProbeRecord struct {
+	Actor     ActorID   `json:"actor"`
+	Addr      MultiAddr `json:"addr"`
+	Timestamp uint64    `json:"timestamp"`
+	IsOnline  bool      `json:"isOnline"`
+	Latency   uint64    `json:"latency"`
+}
+
+// ProbeAggregate is the downsampled history of a member multiaddr over HISTORY_AGGREGATE_INTERVAL
+type ProbeAggregate struct {
+	Actor  ActorID   `json:"actor"`
+	Addr   MultiAddr `json:"addr"`
+	Start  uint64    `json:"start"`
+	Checks uint64    `json:"checks"`
+	Online uint64    `json:"online"`
+	// Sum, min and max of the latencies of the online probes
+	LatencySum uint64 `json:"latencySum"`
+	MinLatency uint64 `json:"minLatency"`
+	MaxLatency uint64 `json:"maxLatency"`
+}
+
+func (a *ProbeAggregate) add(r *ProbeRecord) {
+	a.Checks++
+	if !r.IsOnline {
+		return
+	}
+
+	if a.Online == 0 || r.Latency < a.MinLatency {
+		a.MinLatency = r.Latency
+	}
+	if r.Latency > a.MaxLatency {
+		a.MaxLatency = r.Latency
+	}
+	a.Online++
+	a.LatencySum += r.Latency
+}
+
+func (a *ProbeAggregate) merge(o *ProbeAggregate) {
+	if o.Online > 0 && (a.Online == 0 || o.MinLatency < a.MinLatency) {
+		a.MinLatency = o.MinLatency
+	}
+	if o.MaxLatency > a.MaxLatency {
+		a.MaxLatency = o.MaxLatency
+	}
+	a.Checks += o.Checks
+	a.Online += o.Online
+	a.LatencySum += o.LatencySum
+}
+
+// HistoryStore persists the probe results on disk. Recent probes are kept as is, older
+// ones are downsampled into hourly aggregates, which are kept up to the retention.
+type HistoryStore struct {
+	ds datastore.Batching
+
+	// suffix of the probe keys, so that the probes of an address checked within the same
+	// second do not overwrite each other. Seeded with the clock to stay unique across restarts
+	seq uint64
+
+	retention    time.Duration
+	rawRetention time.Duration
+}
+
+// OpenHistoryStore opens, or creates, the leveldb backed history store at path
+func OpenHistoryStore(path string, retention time.Duration, rawRetention time.Duration) (*HistoryStore, error) {
+	ds, err := levelds.NewDatastore(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return NewHistoryStore(ds, retention, rawRetention), nil
+}
+
+func NewHistoryStore(ds datastore.Batching, retention time.Duration, rawRetention time.Duration) *HistoryStore {
+	return &HistoryStore{
+		ds:           ds,
+		seq:          uint64(time.Now().UnixNano()),
+		retention:    retention,
+		rawRetention: rawRetention,
+	}
+}
+
+func (h *HistoryStore) Close() error {
+	return h.ds.Close()
+}
+
+// Record stores the result of a probe
+func (h *HistoryStore) Record(ctx context.Context, r ProbeRecord) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return h.ds.Put(ctx, h.probeKey(&r), b)
+}
+
+// Probes returns the raw probes of the member multiaddr checked within [from, to], oldest first
+func (h *HistoryStore) Probes(ctx context.Context, actor ActorID, addr MultiAddr, from uint64, to uint64) ([]ProbeRecord, error) {
+	records := make([]ProbeRecord, 0)
+	err := h.iterate(ctx, historyPrefix(PROBES_PREFIX, actor, addr), func(_ datastore.Key, value []byte) error {
+		var r ProbeRecord
+		if err := json.Unmarshal(value, &r); err != nil {
+			return err
+		}
+		if r.Timestamp >= from && r.Timestamp <= to {
+			records = append(records, r)
+		}
+		return nil
+	})
+	return records, err
+}
+
+// Aggregates returns the downsampled history of the member multiaddr starting within [from, to], oldest first
+func (h *HistoryStore) Aggregates(ctx context.Context, actor ActorID, addr MultiAddr, from uint64, to uint64) ([]ProbeAggregate, error) {
+	aggregates := make([]ProbeAggregate, 0)
+	err := h.iterate(ctx, historyPrefix(AGGREGATES_PREFIX, actor, addr), func(_ datastore.Key, value []byte) error {
+		var a ProbeAggregate
+		if err := json.Unmarshal(value, &a); err != nil {
+			return err
+		}
+		if a.Start >= from && a.Start <= to {
+			aggregates = append(aggregates, a)
+		}
+		return nil
+	})
+	return aggregates, err
+}
+
+// LoadSince returns all the aggregates and raw probes since the unix timestamp, oldest
+// first for each member multiaddr
+func (h *HistoryStore) LoadSince(ctx context.Context, since uint64) ([]ProbeAggregate, []ProbeRecord, error) {
+	aggregates := make([]ProbeAggregate, 0)
+	err := h.iterate(ctx, AGGREGATES_PREFIX, func(_ datastore.Key, value []byte) error {
+		var a ProbeAggregate
+		if err := json.Unmarshal(value, &a); err != nil {
+			return err
+		}
+		if a.Start >= since {
+			aggregates = append(aggregates, a)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	records := make([]ProbeRecord, 0)
+	err = h.iterate(ctx, PROBES_PREFIX, func(_ datastore.Key, value []byte) error {
+		var r ProbeRecord
+		if err := json.Unmarshal(value, &r); err != nil {
+			return err
+		}
+		if r.Timestamp >= since {
+			records = append(records, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return aggregates, records, nil
+}
+
+// Compact downsamples the raw probes older than the raw retention into aggregates and
+// deletes the aggregates older than the retention
+func (h *HistoryStore) Compact(ctx context.Context, now time.Time) error {
+	rawCutoff := uint64(now.Add(-h.rawRetention).Unix())
+	cutoff := uint64(now.Add(-h.retention).Unix())
+	interval := uint64(HISTORY_AGGREGATE_INTERVAL / time.Second)
+
+	aggregates := make(map[datastore.Key]*ProbeAggregate)
+	toDelete := make([]datastore.Key, 0)
+
+	err := h.iterate(ctx, PROBES_PREFIX, func(key datastore.Key, value []byte) error {
+		var r ProbeRecord
+		if err := json.Unmarshal(value, &r); err != nil {
+			return err
+		}
+		if r.Timestamp >= rawCutoff {
+			return nil
+		}
+
+		toDelete = append(toDelete, key)
+		if r.Timestamp < cutoff {
+			return nil
+		}
+
+		start := r.Timestamp / interval * interval
+		aggKey := historyKey(AGGREGATES_PREFIX, r.Actor, r.Addr, start)
+		a, ok := aggregates[aggKey]
+		if !ok {
+			a = &ProbeAggregate{Actor: r.Actor, Addr: r.Addr, Start: start}
+			aggregates[aggKey] = a
+		}
+		a.add(&r)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = h.iterate(ctx, AGGREGATES_PREFIX, func(key datastore.Key, value []byte) error {
+		start, err := strconv.ParseUint(key.BaseNamespace(), 10, 64)
+		if err != nil {
+			return err
+		}
+		if start < cutoff {
+			toDelete = append(toDelete, key)
+			return nil
+		}
+
+		a, ok := aggregates[key]
+		if !ok {
+			return nil
+		}
+
+		var existing ProbeAggregate
+		if err := json.Unmarshal(value, &existing); err != nil {
+			return err
+		}
+		a.merge(&existing)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	batch, err := h.ds.Batch(ctx)
+	if err != nil {
+		return err
+	}
+
+	for key, a := range aggregates {
+		b, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(ctx, key, b); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range toDelete {
+		if err := batch.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	log.Debugw("compacted probe history", "aggregated", len(aggregates), "deleted", len(toDelete))
+
+	return batch.Commit(ctx)
+}
+
+func (h *HistoryStore) iterate(ctx context.Context, prefix string, fn func(key datastore.Key, value []byte) error) error {
+	res, err := h.ds.Query(ctx, query.Query{
+		Prefix: prefix,
+		Orders: []query.Order{query.OrderByKey{}},
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+
+	for r := range res.Next() {
+		if r.Error != nil {
+			return r.Error
+		}
+		if err := fn(datastore.NewKey(r.Key), r.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func historyPrefix(prefix string, actor ActorID, addr MultiAddr) string {
+	return fmt.Sprintf("%s/%d/%s", prefix, actor, historyAddrKey(addr))
+}
+
+// historyKey zero pads the timestamp so that the keys of an address are sorted by time
+func historyKey(prefix string, actor ActorID, addr MultiAddr, timestamp uint64) datastore.Key {
+	return datastore.NewKey(fmt.Sprintf("%s/%020d", historyPrefix(prefix, actor, addr), timestamp))
+}
+
+// probeKey suffixes the history key of the probe with the next sequence number, keeping the
+// probes of an address sorted by time
+func (h *HistoryStore) probeKey(r *ProbeRecord) datastore.Key {
+	seq := atomic.AddUint64(&h.seq, 1)
+	return datastore.NewKey(fmt.Sprintf("%s-%020d", historyKey(PROBES_PREFIX, r.Actor, r.Addr, r.Timestamp), seq))
+}
+
+// historyAddrKey encodes the multiaddr, which contains `/`, into a single key namespace
+func historyAddrKey(addr MultiAddr) string {
+	if addr == MEMBER_RECORD_ADDR {
+		return "member"
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte(addr))
+}
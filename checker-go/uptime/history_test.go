@@ -0,0 +1,61 @@
+package uptime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+)
+
+func TestHistoryKeepsProbesOfSameSecond(t *testing.T) {
+	ctx := context.Background()
+	h := NewHistoryStore(dssync.MutexWrap(datastore.NewMapDatastore()), DEFAULT_HISTORY_RETENTION, DEFAULT_HISTORY_RAW_RETENTION)
+
+	now := time.Now()
+	timestamp := uint64(now.Unix())
+	latencies := []uint64{10, 20, 30}
+	for _, latency := range latencies {
+		r := ProbeRecord{Actor: testMember, Addr: testOnlineAddr, Timestamp: timestamp, IsOnline: true, Latency: latency}
+		if err := h.Record(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := h.Probes(ctx, testMember, testOnlineAddr, timestamp, timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(latencies) {
+		t.Fatalf("%d probes, want %d", len(records), len(latencies))
+	}
+	for i, r := range records {
+		if r.Latency != latencies[i] {
+			t.Errorf("probe %d latency %d, want %d", i, r.Latency, latencies[i])
+		}
+	}
+
+	// once downsampled, the aggregate counts all of them
+	if err := h.Compact(ctx, now.Add(DEFAULT_HISTORY_RAW_RETENTION+time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	aggregates, err := h.Aggregates(ctx, testMember, testOnlineAddr, 0, timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aggregates) != 1 || aggregates[0].Checks != uint64(len(latencies)) {
+		t.Fatalf("aggregates %+v, want one of %d checks", aggregates, len(latencies))
+	}
+	if aggregates[0].MinLatency != 10 || aggregates[0].MaxLatency != 30 || aggregates[0].LatencySum != 60 {
+		t.Errorf("aggregate latencies %+v", aggregates[0])
+	}
+
+	records, err = h.Probes(ctx, testMember, testOnlineAddr, 0, timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Errorf("%d probes left after compaction", len(records))
+	}
+}
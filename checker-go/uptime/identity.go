@@ -0,0 +1,80 @@
+package uptime
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/libp2p/go-libp2p-core/crypto"
+	peerstore "github.com/libp2p/go-libp2p-core/peer"
+)
+
+const IDENTITY_FILE_MODE = 0600 // the key is only readable by the checker
+
+var ErrRegistrationMismatch = errors.New("registered checker does not match the node")
+
+// LoadOrCreateIdentity returns the libp2p key of the checker stored at the path, generating
+// and storing a new ed25519 key if there is none, so that the peer id registered in the actor
+// is the one of the node across restarts
+func LoadOrCreateIdentity(path string) (crypto.PrivKey, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return crypto.UnmarshalPrivateKey(data)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	key, _, err := crypto.GenerateKeyPair(crypto.Ed25519, -1)
+	if err != nil {
+		return nil, err
+	}
+	data, err = crypto.MarshalPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, data, IDENTITY_FILE_MODE); err != nil {
+		return nil, err
+	}
+
+	log.Infow("generated new libp2p identity", "path", path)
+	return key, nil
+}
+
+// checkRegistration checks that the checker registered in the actor has the peer id of the
+// node and announces its addresses, so that the other checkers do not probe another node
+func checkRegistration(info *NodeInfo, peerID peerstore.ID, addrs []MultiAddr) error {
+	registered, err := peerstore.Decode(info.Id)
+	if err != nil {
+		return fmt.Errorf("invalid registered peer id %s: %w", info.Id, err)
+	}
+	if registered != peerID {
+		return fmt.Errorf("%w: registered peer id %s, node is %s", ErrRegistrationMismatch, registered, peerID)
+	}
+	if !sameAddrs(info.Addresses, addrs) {
+		return fmt.Errorf("%w: registered addresses %v, node announces %v", ErrRegistrationMismatch, info.Addresses, addrs)
+	}
+	return nil
+}
+
+func sameAddrs(a []MultiAddr, b []MultiAddr) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := append([]MultiAddr{}, a...)
+	sortedB := append([]MultiAddr{}, b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
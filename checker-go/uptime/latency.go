@@ -0,0 +1,107 @@
+package uptime
+
+import (
+	"math"
+	"sort"
+)
+
+const LATENCY_WINDOW_SIZE = 100 // number of latest probes kept per address
+const LATENCY_EWMA_ALPHA = 0.2  // weight of the latest latency in the moving average
+
+type probeSample struct {
+	isOnline bool
+	latency  uint64
+}
+
+// latencyWindow is a ring buffer of the latest probes of an address
+type latencyWindow struct {
+	samples []probeSample
+	next    int
+	size    int
+}
+
+func newLatencyWindow(size int) *latencyWindow {
+	return &latencyWindow{
+		samples: make([]probeSample, size),
+	}
+}
+
+func (w *latencyWindow) add(s probeSample) {
+	w.samples[w.next] = s
+	w.next = (w.next + 1) % len(w.samples)
+	if w.size < len(w.samples) {
+		w.size++
+	}
+}
+
+// onlineLatencies returns the sorted latencies of the online probes in the window
+func (w *latencyWindow) onlineLatencies() []uint64 {
+	latencies := make([]uint64, 0, w.size)
+	for _, s := range w.samples[:w.size] {
+		if s.isOnline {
+			latencies = append(latencies, s.latency)
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return latencies
+}
+
+func (w *latencyWindow) successRatio() float64 {
+	if w.size == 0 {
+		return 0
+	}
+
+	online := 0
+	for _, s := range w.samples[:w.size] {
+		if s.isOnline {
+			online++
+		}
+	}
+	return float64(online) / float64(w.size)
+}
+
+// percentile uses the nearest rank method on the sorted latencies
+func percentile(sorted []uint64, p float64) uint64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// recordLatency updates the latency statistics of the address with the latest probe.
+// Only online probes contribute to the latency figures, offline ones only lower the
+// success ratio.
+func (h *HealtcheckInfo) recordLatency(info UpInfo) {
+	if h.window == nil {
+		h.window = newLatencyWindow(LATENCY_WINDOW_SIZE)
+	}
+	h.window.add(probeSample{isOnline: info.isOnline, latency: info.latency})
+
+	if info.isOnline {
+		h.latencySum += info.latency
+		h.LatencyCounts++
+		h.AvgLatency = h.latencySum / h.LatencyCounts
+
+		if h.LatencyCounts == 1 {
+			h.EwmaLatency = info.latency
+		} else {
+			h.EwmaLatency = uint64(LATENCY_EWMA_ALPHA*float64(info.latency) + (1-LATENCY_EWMA_ALPHA)*float64(h.EwmaLatency))
+		}
+	}
+
+	latencies := h.window.onlineLatencies()
+	h.MinLatency, h.MaxLatency = 0, 0
+	if len(latencies) > 0 {
+		h.MinLatency = latencies[0]
+		h.MaxLatency = latencies[len(latencies)-1]
+	}
+	h.P50Latency = percentile(latencies, 0.50)
+	h.P95Latency = percentile(latencies, 0.95)
+	h.P99Latency = percentile(latencies, 0.99)
+
+	h.SuccessRatio = h.window.successRatio()
+}
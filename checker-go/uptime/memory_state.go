@@ -0,0 +1,310 @@
+package uptime
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// MemoryState is a StateReader holding the state of the actor in memory. It is meant for
+// tests and local simulations; use Clone to take a snapshot before changing it, as the
+// checker expects the states it reads not to change.
+type MemoryState struct {
+	members         map[ActorID]NodeInfo
+	checkers        map[ActorID]NodeInfo
+	offlineCheckers map[ActorID]Votes
+	totalCheckers   uint64
+	votingDuration  ChainEpoch
+
+	rwLock sync.RWMutex
+}
+
+func NewMemoryState() *MemoryState {
+	return &MemoryState{
+		members:         make(map[ActorID]NodeInfo),
+		checkers:        make(map[ActorID]NodeInfo),
+		offlineCheckers: make(map[ActorID]Votes),
+		votingDuration:  DEFAULT_VOTING_DURATION,
+	}
+}
+
+// Clone returns a deep copy of the state
+func (m *MemoryState) Clone() *MemoryState {
+	m.rwLock.RLock()
+	defer m.rwLock.RUnlock()
+
+	c := NewMemoryState()
+	for actorID, info := range m.members {
+		c.members[actorID] = copyNodeInfo(info)
+	}
+	for actorID, info := range m.checkers {
+		c.checkers[actorID] = copyNodeInfo(info)
+	}
+	for actorID, votes := range m.offlineCheckers {
+		c.offlineCheckers[actorID] = Votes{LastVote: votes.LastVote, Votes: append([]ActorID{}, votes.Votes...)}
+	}
+	c.totalCheckers = m.totalCheckers
+	c.votingDuration = m.votingDuration
+	return c
+}
+
+// SetMember adds or replaces the member
+func (m *MemoryState) SetMember(actorID ActorID, info NodeInfo) {
+	m.rwLock.Lock()
+	defer m.rwLock.Unlock()
+	m.members[actorID] = copyNodeInfo(info)
+}
+
+func (m *MemoryState) RemoveMember(actorID ActorID) {
+	m.rwLock.Lock()
+	defer m.rwLock.Unlock()
+	delete(m.members, actorID)
+}
+
+// SetChecker adds or replaces the checker
+func (m *MemoryState) SetChecker(actorID ActorID, info NodeInfo) {
+	m.rwLock.Lock()
+	defer m.rwLock.Unlock()
+	m.checkers[actorID] = copyNodeInfo(info)
+}
+
+// RemoveChecker removes the checker. As in the actor, the votes on it are kept.
+func (m *MemoryState) RemoveChecker(actorID ActorID) {
+	m.rwLock.Lock()
+	defer m.rwLock.Unlock()
+	delete(m.checkers, actorID)
+}
+
+// SetTotalCheckers sets the number of checkers the eviction threshold is computed against.
+// As in the actor, it does not follow the checkers added or removed.
+func (m *MemoryState) SetTotalCheckers(total uint64) {
+	m.rwLock.Lock()
+	defer m.rwLock.Unlock()
+	m.totalCheckers = total
+}
+
+func (m *MemoryState) SetVotingDuration(duration ChainEpoch) {
+	m.rwLock.Lock()
+	defer m.rwLock.Unlock()
+	m.votingDuration = duration
+}
+
+// SetVotes sets the votes on the reported checker
+func (m *MemoryState) SetVotes(checker ActorID, votes Votes) {
+	m.rwLock.Lock()
+	defer m.rwLock.Unlock()
+	m.offlineCheckers[checker] = Votes{LastVote: votes.LastVote, Votes: append([]ActorID{}, votes.Votes...)}
+}
+
+// RemoveVotes clears the votes on the checker, which is then no longer reported
+func (m *MemoryState) RemoveVotes(checker ActorID) {
+	m.rwLock.Lock()
+	defer m.rwLock.Unlock()
+	delete(m.offlineCheckers, checker)
+}
+
+func (m *MemoryState) ListMembers() ([]ActorID, error) {
+	m.rwLock.RLock()
+	defer m.rwLock.RUnlock()
+	return sortedKeys(m.members), nil
+}
+
+func (m *MemoryState) ListCheckers() ([]ActorID, error) {
+	m.rwLock.RLock()
+	defer m.rwLock.RUnlock()
+	return sortedKeys(m.checkers), nil
+}
+
+func (m *MemoryState) ListMemberMultiAddrs(actorID ActorID) (*[]MultiAddr, error) {
+	d, err := m.GetMemberInfo(actorID)
+	if err != nil || d == nil {
+		return nil, err
+	}
+	return &d.Addresses, nil
+}
+
+func (m *MemoryState) ListCheckerMultiAddrs(actorID ActorID) (*[]MultiAddr, error) {
+	d, err := m.GetCheckerInfo(actorID)
+	if err != nil || d == nil {
+		return nil, err
+	}
+	return &d.Addresses, nil
+}
+
+// GetMemberInfo returns the node info of the member, nil if not registered
+func (m *MemoryState) GetMemberInfo(actorID ActorID) (*NodeInfo, error) {
+	m.rwLock.RLock()
+	defer m.rwLock.RUnlock()
+	return lookupNodeInfo(m.members, actorID), nil
+}
+
+// GetCheckerInfo returns the node info of the checker, nil if not registered
+func (m *MemoryState) GetCheckerInfo(actorID ActorID) (*NodeInfo, error) {
+	m.rwLock.RLock()
+	defer m.rwLock.RUnlock()
+	return lookupNodeInfo(m.checkers, actorID), nil
+}
+
+func (m *MemoryState) GetOfflineCheckers() ([]ActorID, error) {
+	m.rwLock.RLock()
+	defer m.rwLock.RUnlock()
+
+	return sortedVotesKeys(m.offlineCheckers), nil
+}
+
+func (m *MemoryState) HasVotedForReportedChecker(reported ActorID, voter ActorID) (bool, error) {
+	m.rwLock.RLock()
+	defer m.rwLock.RUnlock()
+
+	v, ok := m.offlineCheckers[reported]
+	if !ok {
+		return false, nil
+	}
+	return v.HasVoted(voter)
+}
+
+// GetVotes returns the votes on the reported checker, nil if not reported
+func (m *MemoryState) GetVotes(reported ActorID) (*Votes, error) {
+	m.rwLock.RLock()
+	defer m.rwLock.RUnlock()
+
+	v, ok := m.offlineCheckers[reported]
+	if !ok {
+		return nil, nil
+	}
+	return &Votes{LastVote: v.LastVote, Votes: append([]ActorID{}, v.Votes...)}, nil
+}
+
+// ListVotes returns the votes on all the reported checkers
+func (m *MemoryState) ListVotes() (map[ActorID]*Votes, error) {
+	m.rwLock.RLock()
+	defer m.rwLock.RUnlock()
+
+	votes := make(map[ActorID]*Votes, len(m.offlineCheckers))
+	for actorID, v := range m.offlineCheckers {
+		votes[actorID] = &Votes{LastVote: v.LastVote, Votes: append([]ActorID{}, v.Votes...)}
+	}
+	return votes, nil
+}
+
+func (m *MemoryState) TotalCheckers() uint64 {
+	m.rwLock.RLock()
+	defer m.rwLock.RUnlock()
+	return m.totalCheckers
+}
+
+func (m *MemoryState) VotingDuration() ChainEpoch {
+	m.rwLock.RLock()
+	defer m.rwLock.RUnlock()
+	return m.votingDuration
+}
+
+func (m *MemoryState) HasRegistered(actor ActorID) (bool, error) {
+	m.rwLock.RLock()
+	defer m.rwLock.RUnlock()
+	_, ok := m.checkers[actor]
+	return ok, nil
+}
+
+// Diff returns the changes that transform the prev state into this one
+func (m *MemoryState) Diff(other StateReader) (*StateDiff, error) {
+	prev, ok := other.(*MemoryState)
+	if !ok {
+		return nil, fmt.Errorf("cannot diff %T against a memory state", other)
+	}
+	if prev == m {
+		return &StateDiff{Members: []NodeChange{}, Checkers: []NodeChange{}, OfflineCheckers: []VotesChange{}}, nil
+	}
+
+	prev.rwLock.RLock()
+	defer prev.rwLock.RUnlock()
+	m.rwLock.RLock()
+	defer m.rwLock.RUnlock()
+
+	offline := make([]VotesChange, 0)
+	for _, actorID := range unionIDs(sortedVotesKeys(prev.offlineCheckers), sortedVotesKeys(m.offlineCheckers)) {
+		before, hadBefore := prev.offlineCheckers[actorID]
+		after, hasAfter := m.offlineCheckers[actorID]
+		if hadBefore && hasAfter && reflect.DeepEqual(before, after) {
+			continue
+		}
+
+		var b, a *Votes
+		if hadBefore {
+			b = &before
+		}
+		if hasAfter {
+			a = &after
+		}
+		offline = append(offline, newVotesChange(actorID, b, a))
+	}
+
+	return &StateDiff{
+		Members:         diffNodeMaps(prev.members, m.members),
+		Checkers:        diffNodeMaps(prev.checkers, m.checkers),
+		OfflineCheckers: offline,
+	}, nil
+}
+
+func diffNodeMaps(prev map[ActorID]NodeInfo, cur map[ActorID]NodeInfo) []NodeChange {
+	changes := make([]NodeChange, 0)
+	for _, actorID := range unionIDs(sortedKeys(prev), sortedKeys(cur)) {
+		before, hadBefore := prev[actorID]
+		after, hasAfter := cur[actorID]
+
+		switch {
+		case !hadBefore:
+			changes = append(changes, NodeChange{Actor: actorID, Type: CHANGE_ADDED, After: &after})
+		case !hasAfter:
+			changes = append(changes, NodeChange{Actor: actorID, Type: CHANGE_REMOVED, Before: &before})
+		case !reflect.DeepEqual(before, after):
+			changes = append(changes, NodeChange{Actor: actorID, Type: CHANGE_EDITED, Before: &before, After: &after})
+		}
+	}
+	return changes
+}
+
+func lookupNodeInfo(nodes map[ActorID]NodeInfo, actorID ActorID) *NodeInfo {
+	info, ok := nodes[actorID]
+	if !ok {
+		return nil
+	}
+	info = copyNodeInfo(info)
+	return &info
+}
+
+func copyNodeInfo(info NodeInfo) NodeInfo {
+	info.Addresses = append([]MultiAddr{}, info.Addresses...)
+	return info
+}
+
+func sortedKeys(nodes map[ActorID]NodeInfo) []ActorID {
+	ids := make([]ActorID, 0, len(nodes))
+	for actorID := range nodes {
+		ids = append(ids, actorID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func sortedVotesKeys(votes map[ActorID]Votes) []ActorID {
+	ids := make([]ActorID, 0, len(votes))
+	for actorID := range votes {
+		ids = append(ids, actorID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// unionIDs returns the actor ids in any of the two lists, sorted
+func unionIDs(a []ActorID, b []ActorID) []ActorID {
+	ids := append([]ActorID{}, a...)
+	for _, actorID := range b {
+		if !containsActor(a, actorID) {
+			ids = append(ids, actorID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
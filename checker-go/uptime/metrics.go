@@ -0,0 +1,178 @@
+package uptime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const METRICS_NAMESPACE = "uptime_checker"
+
+// Reasons of a failed probe, used as label of the probe error counter
+const PROBE_ERR_PARSE = "parse"
+const PROBE_ERR_NO_PROBER = "no_prober"
+const PROBE_ERR_CONNECT = "connect"
+const PROBE_ERR_CONNECT_TIMEOUT = "connect_timeout"
+const PROBE_ERR_PING = "ping"
+const PROBE_ERR_PING_TIMEOUT = "ping_timeout"
+const PROBE_ERR_HTTP = "http"
+const PROBE_ERR_HTTP_TIMEOUT = "http_timeout"
+const PROBE_ERR_HTTP_STATUS = "http_status"
+const PROBE_ERR_DIAL = "dial"
+const PROBE_ERR_DIAL_TIMEOUT = "dial_timeout"
+const PROBE_ERR_DNS = "dns"
+
+// Loops of the checker, used as label of the round duration histogram
+const LOOP_MEMBERS = "members"
+const LOOP_CHECKERS = "checkers"
+const LOOP_REPORTED_CHECKERS = "reported_checkers"
+
+const EXIT_CODE_MPOOL_ERROR = "mpool_error"
+
+var methodNames = map[abi.MethodNum]string{
+	INIT_METHOD:           "init",
+	NEW_CHECKER_METHOD:    "new_checker",
+	NEW_MEMBER_METHOD:     "new_member",
+	EDIT_CHECKER_METHOD:   "edit_checker",
+	EDIT_MEMBER_METHOD:    "edit_member",
+	RM_CHCKER_METHOD:      "rm_checker",
+	RM_MEMBER_METHOD:      "rm_member",
+	REPORT_CHECKER_METHOD: "report_checker",
+}
+
+// MetricsRegistry holds all the prometheus metrics of the checker
+var MetricsRegistry = prometheus.NewRegistry()
+
+var (
+	memberUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: METRICS_NAMESPACE,
+		Name:      "member_up",
+		Help:      "Whether the member node was online in the last round.",
+	}, []string{"actor"})
+
+	addressUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: METRICS_NAMESPACE,
+		Name:      "address_up",
+		Help:      "Whether the multiaddr of the member node was online in the last round.",
+	}, []string{"actor", "addr"})
+
+	probeLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: METRICS_NAMESPACE,
+		Name:      "probe_latency_seconds",
+		Help:      "Latency of the successful probes.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 15),
+	}, []string{"protocol"})
+
+	probeErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: METRICS_NAMESPACE,
+		Name:      "probe_errors_total",
+		Help:      "Number of failed probes by reason.",
+	}, []string{"protocol", "reason"})
+
+	messagesSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: METRICS_NAMESPACE,
+		Name:      "messages_total",
+		Help:      "Number of messages sent to the actor by method and exit code.",
+	}, []string{"method", "exit_code"})
+
+	stateLoadFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: METRICS_NAMESPACE,
+		Name:      "state_load_failures_total",
+		Help:      "Number of failures to load the actor state.",
+	})
+
+	roundDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: METRICS_NAMESPACE,
+		Name:      "round_duration_seconds",
+		Help:      "Duration of the rounds of the monitor loops.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 14),
+	}, []string{"loop"})
+)
+
+func init() {
+	MetricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		memberUp,
+		addressUp,
+		probeLatency,
+		probeErrors,
+		messagesSent,
+		stateLoadFailures,
+		roundDuration,
+	)
+}
+
+// MetricsHandler serves the metrics in the prometheus exposition format
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(MetricsRegistry, promhttp.HandlerOpts{})
+}
+
+func observeProbe(info UpInfo) {
+	if info.isOnline {
+		probeLatency.WithLabelValues(info.protocol).Observe(time.Duration(info.latency).Seconds())
+		return
+	}
+	probeErrors.WithLabelValues(info.protocol, info.errReason).Inc()
+}
+
+func observeMemberHealth(actorID ActorID, member *MemberHealthInfo) {
+	actor := fmt.Sprintf("%d", actorID)
+	memberUp.WithLabelValues(actor).Set(boolToGauge(member.IsOnline))
+	for addr, info := range member.Addresses {
+		addressUp.WithLabelValues(actor, addr).Set(boolToGauge(info.IsOnline))
+	}
+}
+
+func forgetMemberHealth(actorID ActorID, member *MemberHealthInfo) {
+	memberUp.DeleteLabelValues(fmt.Sprintf("%d", actorID))
+	for addr := range member.Addresses {
+		forgetAddressHealth(actorID, addr)
+	}
+}
+
+func forgetAddressHealth(actorID ActorID, addr MultiAddr) {
+	addressUp.DeleteLabelValues(fmt.Sprintf("%d", actorID), addr)
+}
+
+func observeMessage(method abi.MethodNum, code exitcode.ExitCode) {
+	messagesSent.WithLabelValues(methodName(method), fmt.Sprintf("%d", code)).Inc()
+}
+
+func observeMessageError(method abi.MethodNum) {
+	messagesSent.WithLabelValues(methodName(method), EXIT_CODE_MPOOL_ERROR).Inc()
+}
+
+func observeRound(loop string, start time.Time) {
+	roundDuration.WithLabelValues(loop).Observe(time.Since(start).Seconds())
+}
+
+// failureReason returns timeoutReason if the probe failed because its context expired
+func failureReason(ctx context.Context, reason string, timeoutReason string) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return timeoutReason
+	}
+	return reason
+}
+
+func methodName(method abi.MethodNum) string {
+	if name, ok := methodNames[method]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", method)
+}
+
+func boolToGauge(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,293 @@
+package uptime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	lapi "github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/v0api"
+	chainTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+)
+
+const MSG_QUEUE_POLL_INTERVAL = 5 * time.Second // interval of the lookups of the pending messages
+const DEFAULT_STUCK_TIMEOUT = 5 * time.Minute   // after which a pending message is repriced
+const DEFAULT_MAX_REPRICES = 3                  // before a stuck message is given up
+
+// Replacements must raise the gas premium by at least a quarter, as lotus ReplaceByFeeRatioDefault
+const REPRICE_NUMERATOR = 5
+const REPRICE_DENOMINATOR = 4
+
+const REPRICE_MAX_FEE_BLOCKS = 20 // blocks the fee cap of a replacement is estimated for
+
+var ErrMessageStuck = errors.New("message not executed after repricing")
+
+// PendingMessage is a message pushed by the MessageQueue and tracked until it is executed
+type PendingMessage struct {
+	method abi.MethodNum
+	cids   []cid.Cid // the message and its replacements, the last one being the current
+
+	lookup *lapi.MsgLookup
+	err    error
+	done   chan struct{}
+
+	lock sync.Mutex
+}
+
+// Done is closed once the message is executed or given up
+func (p *PendingMessage) Done() <-chan struct{} {
+	return p.done
+}
+
+// Wait blocks until the message is executed and returns its lookup, or the error of the push
+// or tracking of the message
+func (p *PendingMessage) Wait(ctx context.Context) (*lapi.MsgLookup, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.done:
+		return p.lookup, p.err
+	}
+}
+
+// Method returns the method called by the message
+func (p *PendingMessage) Method() abi.MethodNum {
+	return p.method
+}
+
+// Cid returns the cid of the current message, the last replacement if repriced
+func (p *PendingMessage) Cid() cid.Cid {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.cids[len(p.cids)-1]
+}
+
+func (p *PendingMessage) replaced(c cid.Cid) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.cids = append(p.cids, c)
+}
+
+func (p *PendingMessage) finish(lookup *lapi.MsgLookup, err error) {
+	p.lookup = lookup
+	p.err = err
+	close(p.done)
+}
+
+// MessageQueue pushes the messages to the mpool and tracks them in the background, so that
+// senders do not block until their messages are executed. Nonces are assigned one message at
+// a time per wallet, messages stuck in the mpool are repriced, and a message identical to a
+// pending one is not pushed again.
+type MessageQueue struct {
+	api v0api.FullNode
+
+	pollInterval time.Duration
+	stuckTimeout time.Duration
+	maxReprices  int
+
+	// the tracking of the pending messages stops once the queue is closed
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	wallets map[address.Address]*sync.Mutex // serializes the nonce assignment per wallet
+	pending map[string]*PendingMessage      // the pending messages, by content
+
+	lock sync.Mutex
+}
+
+func NewMessageQueue(api v0api.FullNode) *MessageQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &MessageQueue{
+		api:          api,
+		pollInterval: MSG_QUEUE_POLL_INTERVAL,
+		stuckTimeout: DEFAULT_STUCK_TIMEOUT,
+		ctx:          ctx,
+		cancel:       cancel,
+		maxReprices:  DEFAULT_MAX_REPRICES,
+		wallets:      make(map[address.Address]*sync.Mutex),
+		pending:      make(map[string]*PendingMessage),
+	}
+}
+
+// SetStuckTimeout sets the duration after which a pending message is repriced
+func (q *MessageQueue) SetStuckTimeout(timeout time.Duration) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.stuckTimeout = timeout
+}
+
+// SetMaxReprices sets the number of attempts to reprice a stuck message before it is given up
+func (q *MessageQueue) SetMaxReprices(maxReprices int) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.maxReprices = maxReprices
+}
+
+// Close stops tracking the pending messages, which finish with the error of the cancelled
+// context. The messages already pushed may still be executed.
+func (q *MessageQueue) Close() {
+	q.cancel()
+}
+
+// Push pushes the message and tracks it until it is executed. If an identical message is
+// already pending, it is returned instead.
+func (q *MessageQueue) Push(ctx context.Context, msg *chainTypes.Message) (*PendingMessage, error) {
+	key := messageKey(msg)
+
+	q.lock.Lock()
+	if p, ok := q.pending[key]; ok {
+		q.lock.Unlock()
+		log.Debugw("identical message already pending", "method", methodName(msg.Method), "cid", p.Cid())
+		return p, nil
+	}
+	wallet := q.wallet(msg.From)
+	q.lock.Unlock()
+
+	wallet.Lock()
+	defer wallet.Unlock()
+
+	// an identical message may have been pushed while waiting for the wallet
+	q.lock.Lock()
+	if p, ok := q.pending[key]; ok {
+		q.lock.Unlock()
+		return p, nil
+	}
+	q.lock.Unlock()
+
+	smsg, err := q.api.MpoolPushMessage(ctx, msg, nil)
+	if err != nil {
+		observeMessageError(msg.Method)
+		return nil, err
+	}
+
+	p := &PendingMessage{
+		method: msg.Method,
+		cids:   []cid.Cid{smsg.Cid()},
+		done:   make(chan struct{}),
+	}
+
+	q.lock.Lock()
+	q.pending[key] = p
+	pollInterval, stuckTimeout, maxReprices := q.pollInterval, q.stuckTimeout, q.maxReprices
+	q.lock.Unlock()
+
+	go q.track(key, p, smsg, pollInterval, stuckTimeout, maxReprices)
+
+	return p, nil
+}
+
+// track polls the message until it is executed, repricing it when it is stuck. The tracking
+// outlives the context of the push, as senders do not wait for the message, and stops when
+// the queue is closed. A message that cannot be searched for counts as stuck, so that it is
+// given up after as many attempts as a message that is not executed.
+func (q *MessageQueue) track(key string, p *PendingMessage, smsg *chainTypes.SignedMessage, pollInterval time.Duration, stuckTimeout time.Duration, maxReprices int) {
+	ctx := q.ctx
+	defer func() {
+		q.lock.Lock()
+		delete(q.pending, key)
+		q.lock.Unlock()
+	}()
+
+	reprices := 0
+	since := time.Now()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Debugw("stop tracking message", "method", methodName(p.method), "cid", p.Cid())
+			p.finish(nil, ctx.Err())
+			return
+		case <-ticker.C:
+		}
+
+		// a replaced message is found by the cid of any of its versions
+		lookup, err := q.api.StateSearchMsg(ctx, p.Cid())
+		if err != nil {
+			log.Warnw("cannot search message", "method", methodName(p.method), "cid", p.Cid(), "err", err)
+		} else if lookup != nil {
+			observeMessage(smsg.Message.Method, lookup.Receipt.ExitCode)
+			p.finish(lookup, nil)
+			return
+		}
+
+		if time.Since(since) < stuckTimeout {
+			continue
+		}
+		if reprices >= maxReprices {
+			log.Errorw("giving up stuck message", "method", methodName(p.method), "cid", p.Cid(), "reprices", reprices)
+			p.finish(nil, fmt.Errorf("%w: %s", ErrMessageStuck, p.Cid()))
+			return
+		}
+
+		// a failed reprice uses up an attempt too, so that the message is given up eventually
+		reprices++
+		since = time.Now()
+
+		replacement, err := q.reprice(ctx, smsg)
+		if err != nil {
+			log.Errorw("cannot reprice stuck message", "method", methodName(p.method), "cid", p.Cid(), "err", err)
+			continue
+		}
+
+		log.Warnw("repriced stuck message", "method", methodName(p.method), "cid", smsg.Cid(), "replacement", replacement.Cid(), "premium", replacement.Message.GasPremium)
+		smsg = replacement
+		p.replaced(replacement.Cid())
+	}
+}
+
+// reprice pushes a replacement of the message, with the same nonce and a higher gas premium
+func (q *MessageQueue) reprice(ctx context.Context, smsg *chainTypes.SignedMessage) (*chainTypes.SignedMessage, error) {
+	msg := smsg.Message
+
+	minPremium := big.Add(big.Div(big.Mul(msg.GasPremium, big.NewInt(REPRICE_NUMERATOR)), big.NewInt(REPRICE_DENOMINATOR)), big.NewInt(1))
+	msg.GasPremium = minPremium
+
+	feeCap, err := q.api.GasEstimateFeeCap(ctx, &msg, REPRICE_MAX_FEE_BLOCKS, chainTypes.EmptyTSK)
+	if err != nil {
+		return nil, err
+	}
+	msg.GasFeeCap = big.Max(feeCap, big.Max(msg.GasFeeCap, msg.GasPremium))
+
+	wallet := q.walletLock(msg.From)
+	wallet.Lock()
+	defer wallet.Unlock()
+
+	replacement, err := q.api.WalletSignMessage(ctx, msg.From, &msg)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := q.api.MpoolPush(ctx, replacement); err != nil {
+		return nil, err
+	}
+	return replacement, nil
+}
+
+func (q *MessageQueue) walletLock(from address.Address) *sync.Mutex {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.wallet(from)
+}
+
+// wallet returns the lock of the wallet, q.lock must be held
+func (q *MessageQueue) wallet(from address.Address) *sync.Mutex {
+	wallet, ok := q.wallets[from]
+	if !ok {
+		wallet = &sync.Mutex{}
+		q.wallets[from] = wallet
+	}
+	return wallet
+}
+
+// messageKey identifies the content of a message, regardless of its nonce and gas
+func messageKey(msg *chainTypes.Message) string {
+	return fmt.Sprintf("%s/%s/%d/%s/%x", msg.From, msg.To, msg.Method, msg.Value, msg.Params)
+}
@@ -0,0 +1,143 @@
+package uptime
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/crypto"
+	lapi "github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/v0api"
+	chainTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+)
+
+// mpoolNode is a node whose mpool never executes the messages, searching them failing with
+// searchErr
+type mpoolNode struct {
+	v0api.FullNode
+
+	searchErr error
+
+	lock     sync.Mutex
+	reprices int
+}
+
+func (n *mpoolNode) MpoolPushMessage(ctx context.Context, msg *chainTypes.Message, spec *lapi.MessageSendSpec) (*chainTypes.SignedMessage, error) {
+	return &chainTypes.SignedMessage{Message: *msg, Signature: crypto.Signature{Type: crypto.SigTypeBLS}}, nil
+}
+
+func (n *mpoolNode) StateSearchMsg(ctx context.Context, msg cid.Cid) (*lapi.MsgLookup, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, n.searchErr
+}
+
+func (n *mpoolNode) GasEstimateFeeCap(ctx context.Context, msg *chainTypes.Message, maxqueueblks int64, tsk chainTypes.TipSetKey) (big.Int, error) {
+	return msg.GasFeeCap, nil
+}
+
+func (n *mpoolNode) WalletSignMessage(ctx context.Context, from address.Address, msg *chainTypes.Message) (*chainTypes.SignedMessage, error) {
+	return &chainTypes.SignedMessage{Message: *msg, Signature: crypto.Signature{Type: crypto.SigTypeBLS}}, nil
+}
+
+func (n *mpoolNode) MpoolPush(ctx context.Context, smsg *chainTypes.SignedMessage) (cid.Cid, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.reprices++
+	return smsg.Cid(), nil
+}
+
+func newTestQueue(node *mpoolNode) *MessageQueue {
+	q := NewMessageQueue(node)
+	q.pollInterval = 10 * time.Millisecond
+	q.SetStuckTimeout(20 * time.Millisecond)
+	q.SetMaxReprices(2)
+	return q
+}
+
+func newTestMessage(t *testing.T) *chainTypes.Message {
+	from, err := address.NewIDAddress(testSelf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := address.NewIDAddress(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &chainTypes.Message{
+		From:       from,
+		To:         to,
+		Method:     REPORT_CHECKER_METHOD,
+		Value:      big.Zero(),
+		GasFeeCap:  big.NewInt(100),
+		GasPremium: big.NewInt(100),
+	}
+}
+
+func TestMessageQueueGivesUpStuckMessage(t *testing.T) {
+	node := &mpoolNode{}
+	q := newTestQueue(node)
+	defer q.Close()
+
+	p, err := q.Push(context.Background(), newTestMessage(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := p.Wait(ctx); !errors.Is(err, ErrMessageStuck) {
+		t.Fatalf("error %v, want %v", err, ErrMessageStuck)
+	}
+	if node.reprices != 2 {
+		t.Errorf("repriced %d times, want 2", node.reprices)
+	}
+}
+
+func TestMessageQueueGivesUpUnsearchableMessage(t *testing.T) {
+	node := &mpoolNode{searchErr: errors.New("node unreachable")}
+	q := newTestQueue(node)
+	defer q.Close()
+
+	p, err := q.Push(context.Background(), newTestMessage(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := p.Wait(ctx); !errors.Is(err, ErrMessageStuck) {
+		t.Fatalf("error %v, want %v", err, ErrMessageStuck)
+	}
+}
+
+func TestMessageQueueCloseStopsTracking(t *testing.T) {
+	node := &mpoolNode{}
+	q := newTestQueue(node)
+	q.SetStuckTimeout(time.Hour)
+
+	p, err := q.Push(context.Background(), newTestMessage(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := p.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error %v, want %v", err, context.Canceled)
+	}
+
+	waitFor(t, time.Second, func() bool {
+		q.lock.Lock()
+		defer q.lock.Unlock()
+		return len(q.pending) == 0
+	})
+}
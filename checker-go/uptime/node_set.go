@@ -0,0 +1,105 @@
+package uptime
+
+import (
+	"sort"
+)
+
+// nodeSet tracks the members, or the checkers, of the actor and their addresses across state
+// changes. The first state is listed in full, the next ones are applied as diffs.
+type nodeSet struct {
+	checkers bool // whether the set tracks the checkers instead of the members
+
+	state *CacheState
+	addrs map[ActorID]*[]MultiAddr
+}
+
+func newNodeSet(checkers bool) *nodeSet {
+	return &nodeSet{
+		checkers: checkers,
+		addrs:    make(map[ActorID]*[]MultiAddr),
+	}
+}
+
+// sync updates the set to the state and returns the changes of the nodes, nil if the state
+// did not change
+func (n *nodeSet) sync(state *CacheState) ([]NodeChange, error) {
+	if state == n.state {
+		return nil, nil
+	}
+
+	var changes []NodeChange
+	var err error
+	if n.state == nil {
+		changes, err = n.list(state)
+	} else {
+		changes, err = n.diff(state)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		if change.Type == CHANGE_REMOVED {
+			delete(n.addrs, change.Actor)
+			continue
+		}
+		addrs := change.After.Addresses
+		n.addrs[change.Actor] = &addrs
+	}
+	n.state = state
+
+	return changes, nil
+}
+
+// list returns all the nodes of the state as added
+func (n *nodeSet) list(state *CacheState) ([]NodeChange, error) {
+	var ids []ActorID
+	var err error
+	if n.checkers {
+		ids, err = state.ListCheckers()
+	} else {
+		ids, err = state.ListMembers()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]NodeChange, 0, len(ids))
+	for _, actorID := range ids {
+		var info *NodeInfo
+		if n.checkers {
+			info, err = state.GetCheckerInfo(actorID)
+		} else {
+			info, err = state.GetMemberInfo(actorID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if info == nil {
+			continue
+		}
+		changes = append(changes, NodeChange{Actor: actorID, Type: CHANGE_ADDED, After: info})
+	}
+	return changes, nil
+}
+
+func (n *nodeSet) diff(state *CacheState) ([]NodeChange, error) {
+	d, err := state.Diff(n.state)
+	if err != nil {
+		return nil, err
+	}
+	if n.checkers {
+		return d.Checkers, nil
+	}
+	return d.Members, nil
+}
+
+// ids returns the actor ids of the nodes, sorted
+func (n *nodeSet) ids() []ActorID {
+	ids := make([]ActorID, 0, len(n.addrs))
+	for actorID := range n.addrs {
+		ids = append(ids, actorID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
@@ -0,0 +1,56 @@
+package uptime
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const DEFAULT_PROBE_CONCURRENCY = 16
+const DEFAULT_ROUND_TIMEOUT = 2 * PING_TIMEOUT
+const MIN_ROUND_TIMEOUT = PING_TIMEOUT + ROUND_TIMEOUT_MARGIN // so that a probe of the round can time out by itself
+const ROUND_TIMEOUT_MARGIN = 10 * time.Second
+
+// runRound runs the jobs with at most `concurrency` of them in flight. All the jobs share a
+// context that expires after `timeout`, so the round is bounded no matter how many nodes are
+// unreachable. Jobs are only started while `jobTimeout` is left before the deadline, so that
+// a job that hangs hits its own timeout rather than the deadline of the round; the others are
+// skipped. Returns the number of jobs that were started.
+func runRound(ctx context.Context, concurrency int, timeout time.Duration, jobTimeout time.Duration, n int, job func(ctx context.Context, i int)) int {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	rctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	startCtx, cancelStart := context.WithTimeout(rctx, timeout-jobTimeout)
+	defer cancelStart()
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	started := 0
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-startCtx.Done():
+			log.Warnw("round deadline reached, skipping remaining jobs", "skipped", n-i)
+			wg.Wait()
+			return started
+		}
+
+		started++
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			job(rctx, i)
+		}(i)
+	}
+
+	wg.Wait()
+	return started
+}
@@ -0,0 +1,272 @@
+package uptime
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/host"
+	peerstore "github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
+	madns "github.com/multiformats/go-multiaddr-dns"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// Prober checks whether a node is reachable through one of its multiaddrs
+type Prober interface {
+	Probe(ctx context.Context, addr MultiAddr) UpInfo
+}
+
+// ProberFunc adapts a plain function to the Prober interface
+type ProberFunc func(ctx context.Context, addr MultiAddr) UpInfo
+
+func (f ProberFunc) Probe(ctx context.Context, addr MultiAddr) UpInfo {
+	return f(ctx, addr)
+}
+
+// ProberRegistry selects the prober of a multiaddr based on its protocol components.
+// The healthcheck suffix (e.g. `/http/get/...` or `/ping`) takes precedence, then the
+// protocols of the address are matched from the last one to the first one, so that
+// `/dns4/example.com/tcp/80` is probed with the `tcp` prober and `/dns4/example.com`
+// with the `dns4` prober.
+type ProberRegistry struct {
+	probers map[string]Prober
+
+	rwLock sync.RWMutex
+}
+
+func NewProberRegistry() *ProberRegistry {
+	return &ProberRegistry{
+		probers: make(map[string]Prober),
+	}
+}
+
+// NewDefaultProberRegistry returns the registry of the probers of the checker: libp2p ping for
+// `/ping` and `/p2p` addresses, the http healthcheck, tcp and dns
+func NewDefaultProberRegistry(node host.Host, ping *ping.PingService) *ProberRegistry {
+	probers := NewProberRegistry()
+	pingProber := NewLibp2pPingProber(node, ping)
+	probers.Register(PING_HEALTHCHECK, pingProber)
+	probers.Register("p2p", pingProber)
+	probers.Register(HTTP_HEALTHCHECK, NewHTTPProber(&http.Client{}))
+	probers.Register("tcp", NewTCPProber())
+	dnsProber := NewDNSProber()
+	probers.Register("dns", dnsProber)
+	probers.Register("dns4", dnsProber)
+	probers.Register("dns6", dnsProber)
+	return probers
+}
+
+// Register sets the prober for the protocol name, replacing the existing one if any
+func (r *ProberRegistry) Register(protocol string, p Prober) {
+	r.rwLock.Lock()
+	defer r.rwLock.Unlock()
+	r.probers[protocol] = p
+}
+
+// Lookup returns the prober to use for the multiaddr
+func (r *ProberRegistry) Lookup(addr MultiAddr) (Prober, error) {
+	_, p, err := r.lookup(addr)
+	return p, err
+}
+
+// lookup also returns the protocol name the prober was selected for
+func (r *ProberRegistry) lookup(addr MultiAddr) (string, Prober, error) {
+	base, hc, err := splitHealthcheckAddr(addr)
+	if err != nil {
+		return "", nil, err
+	}
+
+	names := make([]string, 0)
+	if hc.protocol != "" {
+		names = append(names, hc.protocol)
+	}
+	protocols := base.Protocols()
+	for i := len(protocols) - 1; i >= 0; i-- {
+		names = append(names, protocols[i].Name)
+	}
+
+	r.rwLock.RLock()
+	defer r.rwLock.RUnlock()
+
+	for _, name := range names {
+		if p, ok := r.probers[name]; ok {
+			return name, p, nil
+		}
+	}
+	return "", nil, fmt.Errorf("no prober registered for multiaddr %s", addr)
+}
+
+// Probe checks the multiaddr with the matching prober. The node is reported offline
+// if no prober supports the multiaddr.
+func (r *ProberRegistry) Probe(ctx context.Context, addr MultiAddr) UpInfo {
+	protocol, p, err := r.lookup(addr)
+	if err != nil {
+		log.Errorw("cannot find prober for multi addr", "addr", addr, "err", err)
+		upInfo := newUpInfo()
+		upInfo.errReason = PROBE_ERR_NO_PROBER
+		return upInfo
+	}
+
+	upInfo := p.Probe(ctx, addr)
+	upInfo.protocol = protocol
+	return upInfo
+}
+
+// Libp2pPingProber connects to the peer in the `/p2p/` component and pings it with the libp2p ping protocol
+type Libp2pPingProber struct {
+	node host.Host
+	ping *ping.PingService
+}
+
+func NewLibp2pPingProber(node host.Host, ping *ping.PingService) *Libp2pPingProber {
+	return &Libp2pPingProber{node: node, ping: ping}
+}
+
+func (p *Libp2pPingProber) Probe(ctx context.Context, addrStr MultiAddr) UpInfo {
+	upInfo := newUpInfo()
+
+	addr, _, err := splitHealthcheckAddr(addrStr)
+	if err != nil {
+		log.Errorw("cannot parse multi addr", "addr", addrStr, "err", err)
+		upInfo.errReason = PROBE_ERR_PARSE
+		return upInfo
+	}
+
+	peer, err := peerstore.AddrInfoFromP2pAddr(addr)
+	if err != nil {
+		log.Errorw("cannot add multi addr", "addr", addr)
+		upInfo.errReason = PROBE_ERR_PARSE
+		return upInfo
+	}
+
+	log.Debugw("addr for peer", "peer", peer)
+
+	now := time.Now()
+
+	cctx, cancel := context.WithTimeout(ctx, PING_TIMEOUT)
+	defer cancel()
+
+	if err := p.node.Connect(cctx, *peer); err != nil {
+		log.Errorw("cannot connect to multi addr", "peer", peer.ID, "err", err, "addr", addr)
+		upInfo.errReason = failureReason(cctx, PROBE_ERR_CONNECT, PROBE_ERR_CONNECT_TIMEOUT)
+		return upInfo
+	}
+
+	ch := p.ping.Ping(cctx, peer.ID)
+	res := <-ch
+	log.Debugw("got ping response!", "RTT:", res.RTT, "res", res)
+
+	if res.Error != nil {
+		log.Errorw("cannot ping peer", "peer", peer.ID, "err", res.Error, "addr", addr)
+		upInfo.errReason = failureReason(cctx, PROBE_ERR_PING, PROBE_ERR_PING_TIMEOUT)
+		return upInfo
+	}
+
+	upInfo.isOnline = true
+	upInfo.checkedTime = uint64(time.Now().Unix())
+	upInfo.latency = uint64(time.Since(now))
+
+	return upInfo
+}
+
+// TCPProber considers the node online if a tcp connection can be established
+type TCPProber struct {
+	dialer net.Dialer
+}
+
+func NewTCPProber() *TCPProber {
+	return &TCPProber{}
+}
+
+func (p *TCPProber) Probe(ctx context.Context, addrStr MultiAddr) UpInfo {
+	upInfo := newUpInfo()
+
+	addr, _, err := splitHealthcheckAddr(addrStr)
+	if err != nil {
+		log.Errorw("cannot parse multi addr", "addr", addrStr, "err", err)
+		upInfo.errReason = PROBE_ERR_PARSE
+		return upInfo
+	}
+
+	network, hostPort, err := manet.DialArgs(addr)
+	if err != nil {
+		log.Errorw("cannot convert multi addr to tcp address", "addr", addr, "err", err)
+		upInfo.errReason = PROBE_ERR_PARSE
+		return upInfo
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, PING_TIMEOUT)
+	defer cancel()
+
+	now := time.Now()
+
+	conn, err := p.dialer.DialContext(cctx, network, hostPort)
+	if err != nil {
+		log.Errorw("cannot dial tcp address", "addr", addr, "err", err)
+		upInfo.errReason = failureReason(cctx, PROBE_ERR_DIAL, PROBE_ERR_DIAL_TIMEOUT)
+		return upInfo
+	}
+	conn.Close()
+
+	upInfo.isOnline = true
+	upInfo.checkedTime = uint64(time.Now().Unix())
+	upInfo.latency = uint64(time.Since(now))
+
+	return upInfo
+}
+
+// DNSProber considers the node online if its dns multiaddr resolves to at least one address
+type DNSProber struct {
+	resolver *madns.Resolver
+}
+
+func NewDNSProber() *DNSProber {
+	return &DNSProber{resolver: madns.DefaultResolver}
+}
+
+func (p *DNSProber) Probe(ctx context.Context, addrStr MultiAddr) UpInfo {
+	upInfo := newUpInfo()
+
+	addr, _, err := splitHealthcheckAddr(addrStr)
+	if err != nil {
+		log.Errorw("cannot parse multi addr", "addr", addrStr, "err", err)
+		upInfo.errReason = PROBE_ERR_PARSE
+		return upInfo
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, PING_TIMEOUT)
+	defer cancel()
+
+	now := time.Now()
+
+	resolved, err := p.resolver.Resolve(cctx, addr)
+	if err != nil {
+		log.Errorw("cannot resolve dns multi addr", "addr", addr, "err", err)
+		upInfo.errReason = PROBE_ERR_DNS
+		return upInfo
+	}
+
+	if len(resolved) == 0 {
+		log.Errorw("dns multi addr resolved to no address", "addr", addr)
+		upInfo.errReason = PROBE_ERR_DNS
+		return upInfo
+	}
+
+	upInfo.isOnline = true
+	upInfo.checkedTime = uint64(time.Now().Unix())
+	upInfo.latency = uint64(time.Since(now))
+
+	return upInfo
+}
+
+func newUpInfo() UpInfo {
+	return UpInfo{
+		isOnline:    false,
+		latency:     uint64(0),
+		checkedTime: uint64(time.Now().Unix()),
+	}
+}
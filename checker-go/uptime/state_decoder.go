@@ -0,0 +1,175 @@
+package uptime
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+// Versions of the state layout of the actor
+const STATE_VERSION_0 = 0 // members, checkers, offline_checkers and total_checkers
+const STATE_VERSION_1 = 1 // adds voting_duration
+
+// Fields of the actor state, in the order of the HamtState struct of the actor
+const STATE_FIELD_MEMBERS = "members"
+const STATE_FIELD_CHECKERS = "checkers"
+const STATE_FIELD_OFFLINE_CHECKERS = "offline_checkers"
+const STATE_FIELD_TOTAL_CHECKERS = "total_checkers"
+const STATE_FIELD_VOTING_DURATION = "voting_duration"
+
+var stateFields = map[uint64][]string{
+	STATE_VERSION_0: {STATE_FIELD_MEMBERS, STATE_FIELD_CHECKERS, STATE_FIELD_OFFLINE_CHECKERS, STATE_FIELD_TOTAL_CHECKERS},
+	STATE_VERSION_1: {STATE_FIELD_MEMBERS, STATE_FIELD_CHECKERS, STATE_FIELD_OFFLINE_CHECKERS, STATE_FIELD_TOTAL_CHECKERS, STATE_FIELD_VOTING_DURATION},
+}
+
+// UnmarshalCBOR decodes the state of the actor, encoded either as a map keyed by the field
+// names, as serde derives it, or as a tuple of the fields in order. The version is detected
+// from the fields present; unknown, missing or duplicated fields fail the decoding instead of
+// being ignored, so that a change of the actor state is not silently misread.
+func (t *HAMTStateInner) UnmarshalCBOR(r io.Reader) (err error) {
+	*t = HAMTStateInner{}
+
+	cr := cbg.NewCborReader(r)
+
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}()
+
+	switch maj {
+	case cbg.MajMap:
+		return t.unmarshalMap(cr, extra)
+	case cbg.MajArray:
+		return t.unmarshalTuple(cr, extra)
+	default:
+		return fmt.Errorf("HAMTStateInner: unexpected cbor major type %d, expected a map or an array", maj)
+	}
+}
+
+func (t *HAMTStateInner) unmarshalMap(cr *cbg.CborReader, n uint64) error {
+	seen := make(map[string]bool)
+	for i := uint64(0); i < n; i++ {
+		name, err := cbg.ReadString(cr)
+		if err != nil {
+			return err
+		}
+		if seen[name] {
+			return fmt.Errorf("HAMTStateInner: duplicated field %q", name)
+		}
+		seen[name] = true
+
+		if err := t.unmarshalField(cr, name); err != nil {
+			return err
+		}
+	}
+
+	for version, fields := range stateFields {
+		if len(fields) != len(seen) {
+			continue
+		}
+		matches := true
+		for _, name := range fields {
+			matches = matches && seen[name]
+		}
+		if matches {
+			t.setVersion(version)
+			return nil
+		}
+	}
+	return fmt.Errorf("HAMTStateInner: fields %v do not match any known state version", sortedNames(seen))
+}
+
+func (t *HAMTStateInner) unmarshalTuple(cr *cbg.CborReader, n uint64) error {
+	for version, fields := range stateFields {
+		if uint64(len(fields)) != n {
+			continue
+		}
+		for _, name := range fields {
+			if err := t.unmarshalField(cr, name); err != nil {
+				return err
+			}
+		}
+		t.setVersion(version)
+		return nil
+	}
+	return fmt.Errorf("HAMTStateInner: tuple of %d fields does not match any known state version", n)
+}
+
+func (t *HAMTStateInner) setVersion(version uint64) {
+	t.Version = version
+	if version == STATE_VERSION_0 {
+		// the actor used the default before the duration was stored
+		t.VotingDuration = DEFAULT_VOTING_DURATION
+	}
+}
+
+func (t *HAMTStateInner) unmarshalField(cr *cbg.CborReader, name string) error {
+	var err error
+	switch name {
+	case STATE_FIELD_MEMBERS:
+		t.Members, err = cbg.ReadCid(cr)
+	case STATE_FIELD_CHECKERS:
+		t.Checkers, err = cbg.ReadCid(cr)
+	case STATE_FIELD_OFFLINE_CHECKERS:
+		t.OfflineCheckers, err = cbg.ReadCid(cr)
+	case STATE_FIELD_TOTAL_CHECKERS:
+		t.TotalCheckers, err = readUint64(cr)
+	case STATE_FIELD_VOTING_DURATION:
+		t.VotingDuration, err = readInt64(cr)
+	default:
+		return fmt.Errorf("HAMTStateInner: unknown field %q", name)
+	}
+	if err != nil {
+		return fmt.Errorf("HAMTStateInner: cannot read field %s: %w", name, err)
+	}
+	return nil
+}
+
+func readUint64(cr *cbg.CborReader) (uint64, error) {
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return 0, err
+	}
+	if maj != cbg.MajUnsignedInt {
+		return 0, fmt.Errorf("wrong type for uint64 field: %d", maj)
+	}
+	return extra, nil
+}
+
+func readInt64(cr *cbg.CborReader) (int64, error) {
+	maj, extra, err := cr.ReadHeader()
+	if err != nil {
+		return 0, err
+	}
+	v := int64(extra)
+	switch maj {
+	case cbg.MajUnsignedInt:
+		if v < 0 {
+			return 0, fmt.Errorf("int64 positive overflow")
+		}
+		return v, nil
+	case cbg.MajNegativeInt:
+		if v < 0 {
+			return 0, fmt.Errorf("int64 negative overflow")
+		}
+		return -1 - v, nil
+	default:
+		return 0, fmt.Errorf("wrong type for int64 field: %d", maj)
+	}
+}
+
+func sortedNames(m map[string]bool) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,218 @@
+package uptime
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+// The cids of the HAMTs in the testdata state, blake2b-256 dag-cbor cids of the field names
+const testMembersCid = "bafy2bzaceamd5smtdajumfmvnvoxke52snnapmf2betpbhipzckennzb5fk5u"
+const testCheckersCid = "bafy2bzacecxgz2d6s7gjh6kpdnqq2kaithv6yeurlhtxmgzpbdnbns2u5v4vq"
+const testOfflineCheckersCid = "bafy2bzacebzcbjdfmyki7lgqkn43gkysi373b2qiftuy3kcintiqbvtuwr7s4"
+
+// goldenState is the state written by the write_state_vectors test of the actor
+const goldenState = "state_v1_map.cbor"
+
+func readGoldenState(t *testing.T) []byte {
+	data, err := os.ReadFile(filepath.Join("testdata", goldenState))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func checkTestState(t *testing.T, st *HAMTStateInner, version uint64, votingDuration ChainEpoch) {
+	t.Helper()
+
+	if st.Members.String() != testMembersCid {
+		t.Errorf("members %s, want %s", st.Members, testMembersCid)
+	}
+	if st.Checkers.String() != testCheckersCid {
+		t.Errorf("checkers %s, want %s", st.Checkers, testCheckersCid)
+	}
+	if st.OfflineCheckers.String() != testOfflineCheckersCid {
+		t.Errorf("offline checkers %s, want %s", st.OfflineCheckers, testOfflineCheckersCid)
+	}
+	if st.TotalCheckers != 3 {
+		t.Errorf("total checkers %d, want 3", st.TotalCheckers)
+	}
+	if st.VotingDuration != votingDuration {
+		t.Errorf("voting duration %d, want %d", st.VotingDuration, votingDuration)
+	}
+	if st.Version != version {
+		t.Errorf("version %d, want %d", st.Version, version)
+	}
+}
+
+func TestDecodeGoldenState(t *testing.T) {
+	var st HAMTStateInner
+	if err := st.UnmarshalCBOR(bytes.NewReader(readGoldenState(t))); err != nil {
+		t.Fatal(err)
+	}
+	checkTestState(t, &st, STATE_VERSION_1, 150)
+}
+
+// The actor never wrote these layouts, their encodings are built by the tests and only
+// check that the decoder accepts them as designed
+var hypotheticalLayouts = []struct {
+	name           string
+	tuple          bool
+	fields         int
+	version        uint64
+	votingDuration ChainEpoch
+}{
+	{"hypothetical v1 tuple", true, 5, STATE_VERSION_1, 150},
+	{"hypothetical v0 map", false, 4, STATE_VERSION_0, DEFAULT_VOTING_DURATION},
+	{"hypothetical v0 tuple", true, 4, STATE_VERSION_0, DEFAULT_VOTING_DURATION},
+}
+
+func TestDecodeHypotheticalLayouts(t *testing.T) {
+	fields := testStateFields(t)
+
+	for _, l := range hypotheticalLayouts {
+		t.Run(l.name, func(t *testing.T) {
+			var st HAMTStateInner
+			if err := st.UnmarshalCBOR(bytes.NewReader(encodeState(t, l.tuple, fields[:l.fields]))); err != nil {
+				t.Fatal(err)
+			}
+			checkTestState(t, &st, l.version, l.votingDuration)
+		})
+	}
+}
+
+func TestDecodeTruncatedState(t *testing.T) {
+	encodings := map[string][]byte{goldenState: readGoldenState(t)}
+	fields := testStateFields(t)
+	for _, l := range hypotheticalLayouts {
+		encodings[l.name] = encodeState(t, l.tuple, fields[:l.fields])
+	}
+
+	for name, data := range encodings {
+		for n := 0; n < len(data); n++ {
+			var st HAMTStateInner
+			if err := st.UnmarshalCBOR(bytes.NewReader(data[:n])); err == nil {
+				t.Errorf("%s truncated to %d bytes decoded", name, n)
+			}
+		}
+	}
+}
+
+// stateField is a field of a state encoded by the tests, a cid, uint or string value
+type stateField struct {
+	name  string
+	value interface{}
+}
+
+// encodeState encodes the fields as a map, or as a tuple of their values
+func encodeState(t *testing.T, tuple bool, fields []stateField) []byte {
+	buf := new(bytes.Buffer)
+	w := cbg.NewCborWriter(buf)
+
+	header := byte(cbg.MajMap)
+	if tuple {
+		header = cbg.MajArray
+	}
+	if err := w.WriteMajorTypeHeader(header, uint64(len(fields))); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range fields {
+		if !tuple {
+			if err := writeCborString(w, f.name); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var err error
+		switch value := f.value.(type) {
+		case cid.Cid:
+			err = cbg.WriteCid(w, value)
+		case uint64:
+			err = w.WriteMajorTypeHeader(cbg.MajUnsignedInt, value)
+		case string:
+			err = writeCborString(w, value)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func writeCborString(w *cbg.CborWriter, s string) error {
+	if err := w.WriteMajorTypeHeader(cbg.MajTextString, uint64(len(s))); err != nil {
+		return err
+	}
+	_, err := w.WriteString(s)
+	return err
+}
+
+// testStateFields returns the fields of the golden state, in the order of the actor
+func testStateFields(t *testing.T) []stateField {
+	cids := make([]cid.Cid, 0, 3)
+	for _, s := range []string{testMembersCid, testCheckersCid, testOfflineCheckersCid} {
+		c, err := cid.Decode(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cids = append(cids, c)
+	}
+	return []stateField{
+		{STATE_FIELD_MEMBERS, cids[0]},
+		{STATE_FIELD_CHECKERS, cids[1]},
+		{STATE_FIELD_OFFLINE_CHECKERS, cids[2]},
+		{STATE_FIELD_TOTAL_CHECKERS, uint64(3)},
+		{STATE_FIELD_VOTING_DURATION, uint64(150)},
+	}
+}
+
+func TestEncodedStateMatchesGolden(t *testing.T) {
+	// the encoding of the tests must be the one of the actor for the invalid states to be relevant
+	if !bytes.Equal(encodeState(t, false, testStateFields(t)), readGoldenState(t)) {
+		t.Fatal("test encoding differs from the golden state")
+	}
+}
+
+func TestDecodeInvalidStateFields(t *testing.T) {
+	fields := testStateFields(t)
+
+	cases := []struct {
+		name   string
+		fields []stateField
+		err    string
+	}{
+		{"unknown field", append(append([]stateField{}, fields...), stateField{"owner", uint64(1)}), "owner"},
+		{"missing field", fields[1:], "fields"},
+		{"duplicated field", append(append([]stateField{}, fields...), fields[0]), "duplicated"},
+		{"wrong type", []stateField{fields[0], fields[1], fields[2], {STATE_FIELD_TOTAL_CHECKERS, "3"}, fields[4]}, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var st HAMTStateInner
+			err := st.UnmarshalCBOR(bytes.NewReader(encodeState(t, false, c.fields)))
+			if err == nil {
+				t.Fatal("decoded")
+			}
+			if !strings.Contains(err.Error(), c.err) {
+				t.Errorf("error %q does not mention %q", err, c.err)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalidStateTuple(t *testing.T) {
+	// a tuple of 6 fields matches no version
+	fields := append(testStateFields(t), stateField{"owner", uint64(1)})
+
+	var st HAMTStateInner
+	if err := st.UnmarshalCBOR(bytes.NewReader(encodeState(t, true, fields))); err == nil {
+		t.Fatal("decoded a tuple of 6 fields")
+	}
+}
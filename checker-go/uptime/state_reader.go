@@ -0,0 +1,26 @@
+package uptime
+
+// StateReader reads the state of the uptime checker actor. HAMTState reads it from the chain
+// and MemoryState holds it in memory, e.g. to test the checker without a lotus node.
+type StateReader interface {
+	ListMembers() ([]ActorID, error)
+	ListCheckers() ([]ActorID, error)
+	ListMemberMultiAddrs(actorID ActorID) (*[]MultiAddr, error)
+	ListCheckerMultiAddrs(actorID ActorID) (*[]MultiAddr, error)
+	GetMemberInfo(actorID ActorID) (*NodeInfo, error)
+	GetCheckerInfo(actorID ActorID) (*NodeInfo, error)
+	GetOfflineCheckers() ([]ActorID, error)
+	HasVotedForReportedChecker(reported ActorID, voter ActorID) (bool, error)
+	HasRegistered(actor ActorID) (bool, error)
+	GetVotes(reported ActorID) (*Votes, error)
+	ListVotes() (map[ActorID]*Votes, error)
+	TotalCheckers() uint64
+	VotingDuration() ChainEpoch
+
+	// Diff returns the changes that transform prev into this state. Both states must be
+	// of the same implementation.
+	Diff(prev StateReader) (*StateDiff, error)
+}
+
+var _ StateReader = (*HAMTState)(nil)
+var _ StateReader = (*MemoryState)(nil)
@@ -0,0 +1,188 @@
+package uptime
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	lapi "github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/v0api"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+)
+
+// StateSource loads the state of the actor. The ChainWatcher and the checker read the state
+// through it, from the chain with NewChainStateSource, or from memory with MemoryStateSource
+// to run the checker without a lotus node.
+type StateSource interface {
+	// Head returns the cid identifying the state of the actor at the tipset, EmptyTSK being
+	// the head of the chain. The state is only reloaded when it changes.
+	Head(ctx context.Context, tsk types.TipSetKey) (cid.Cid, error)
+	// Load returns the state identified by the head
+	Load(ctx context.Context, head cid.Cid) (StateReader, error)
+}
+
+// ChainSource notifies the head changes of the chain and resolves its tipsets. The
+// ChainWatcher follows the lotus node by default; a StateSource that also implements it,
+// such as MemoryStateSource, drives the watcher without a node.
+type ChainSource interface {
+	ChainHead(ctx context.Context) (*types.TipSet, error)
+	ChainNotify(ctx context.Context) (<-chan []*lapi.HeadChange, error)
+	ChainGetTipSetByHeight(ctx context.Context, height abi.ChainEpoch, tsk types.TipSetKey) (*types.TipSet, error)
+}
+
+var _ StateSource = (*chainStateSource)(nil)
+var _ StateSource = (*MemoryStateSource)(nil)
+var _ ChainSource = (*MemoryStateSource)(nil)
+
+// chainStateSource reads the HAMT state of the actor from the chain
+type chainStateSource struct {
+	api   v0api.FullNode
+	actor address.Address
+}
+
+func NewChainStateSource(api v0api.FullNode, actor address.Address) StateSource {
+	return &chainStateSource{api: api, actor: actor}
+}
+
+func (s *chainStateSource) Head(ctx context.Context, tsk types.TipSetKey) (cid.Cid, error) {
+	act, err := s.api.StateGetActor(ctx, s.actor, tsk)
+	if err != nil {
+		return cid.Undef, err
+	}
+	return act.Head, nil
+}
+
+func (s *chainStateSource) Load(ctx context.Context, head cid.Cid) (StateReader, error) {
+	st, err := LoadHAMTStateFromHead(ctx, s.api, head)
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// MemoryStateSource serves a MemoryState as the state of the actor at every tipset. Its head
+// changes with every Update, the states loaded being snapshots that Update does not change.
+//
+// It is also the chain of the watcher: every Update applies a new tipset, at the height of the
+// version of the state. The state being the same at every tipset, the confirmed state is the
+// latest one.
+type MemoryStateSource struct {
+	state   *MemoryState
+	version uint64
+
+	notifs     map[uint64]chan []*lapi.HeadChange
+	nextNotify uint64
+
+	lock sync.Mutex
+}
+
+func NewMemoryStateSource(state *MemoryState) *MemoryStateSource {
+	return &MemoryStateSource{
+		state:  state,
+		notifs: make(map[uint64]chan []*lapi.HeadChange),
+	}
+}
+
+// Update changes the state, the next loads returning the new one
+func (s *MemoryStateSource) Update(fn func(state *MemoryState)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	fn(s.state)
+	s.version++
+
+	ts, err := memoryTipSet(abi.ChainEpoch(s.version))
+	if err != nil {
+		log.Errorw("cannot build the tipset of the memory state", "err", err)
+		return
+	}
+	for _, ch := range s.notifs {
+		// a pending notification already reloads the latest state
+		select {
+		case ch <- []*lapi.HeadChange{{Type: HEAD_CHANGE_APPLY, Val: ts}}:
+		default:
+		}
+	}
+}
+
+func (s *MemoryStateSource) ChainHead(ctx context.Context) (*types.TipSet, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return memoryTipSet(abi.ChainEpoch(s.version))
+}
+
+// ChainNotify notifies the current head, then a new head on every Update until ctx is done
+func (s *MemoryStateSource) ChainNotify(ctx context.Context) (<-chan []*lapi.HeadChange, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ts, err := memoryTipSet(abi.ChainEpoch(s.version))
+	if err != nil {
+		return nil, err
+	}
+
+	id := s.nextNotify
+	s.nextNotify++
+	ch := make(chan []*lapi.HeadChange, 1)
+	ch <- []*lapi.HeadChange{{Type: HEAD_CHANGE_CURRENT, Val: ts}}
+	s.notifs[id] = ch
+
+	go func() {
+		<-ctx.Done()
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		delete(s.notifs, id)
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+func (s *MemoryStateSource) ChainGetTipSetByHeight(ctx context.Context, height abi.ChainEpoch, tsk types.TipSetKey) (*types.TipSet, error) {
+	return memoryTipSet(height)
+}
+
+func (s *MemoryStateSource) Head(ctx context.Context, tsk types.TipSetKey) (cid.Cid, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// the head is the version of the state, inlined in an identity cid
+	version := make([]byte, 8)
+	binary.BigEndian.PutUint64(version, s.version)
+	prefix := cid.Prefix{Version: 1, Codec: cid.Raw, MhType: multihash.IDENTITY, MhLength: -1}
+	return prefix.Sum(version)
+}
+
+func (s *MemoryStateSource) Load(ctx context.Context, head cid.Cid) (StateReader, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.state.Clone(), nil
+}
+
+// memoryTipSet returns the tipset of the memory state at the height, a single block whose
+// cids are the ones of the empty object
+func memoryTipSet(height abi.ChainEpoch) (*types.TipSet, error) {
+	miner, err := address.NewIDAddress(0)
+	if err != nil {
+		return nil, err
+	}
+	prefix := cid.Prefix{Version: 1, Codec: cid.DagCBOR, MhType: multihash.IDENTITY, MhLength: -1}
+	empty, err := prefix.Sum([]byte{0xa0})
+	if err != nil {
+		return nil, err
+	}
+	return types.NewTipSet([]*types.BlockHeader{{
+		Miner:                 miner,
+		Ticket:                &types.Ticket{VRFProof: []byte{}},
+		Height:                height,
+		ParentWeight:          big.Zero(),
+		ParentStateRoot:       empty,
+		ParentMessageReceipts: empty,
+		Messages:              empty,
+		ParentBaseFee:         big.Zero(),
+	}})
+}
@@ -0,0 +1,194 @@
+package uptime
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const STREAM_BUFFER = 256                    // events buffered per stream before it is dropped
+const STREAM_KEEPALIVE = 15 * time.Second    // interval of the keepalive messages
+const STREAM_WRITE_TIMEOUT = 10 * time.Second // max duration of a websocket write
+
+var upgrader = websocket.Upgrader{
+	// the streams are read only, dashboards are usually served from another origin
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// streamEvents streams the events as server sent events. Clients resume with the
+// Last-Event-ID header, which browsers set on reconnect, or the since query param.
+func (a *api) streamEvents(w http.ResponseWriter, r *http.Request) {
+	since, types, ok := parseStreamParams(w, r)
+	if !ok {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, API_ERR_INTERNAL, "streaming is not supported")
+		return
+	}
+
+	backlog, ch, cancel, complete := a.checker.events.Subscribe(since, STREAM_BUFFER)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if !complete {
+		writeSSE(w, resyncEvent(a.checker.events.Seq()))
+	}
+	for _, e := range backlog {
+		if matchesTypes(e, types) {
+			writeSSE(w, e)
+		}
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(STREAM_KEEPALIVE)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			if !matchesTypes(e, types) {
+				continue
+			}
+			writeSSE(w, e)
+			flusher.Flush()
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// streamEventsWebSocket streams the events as json websocket messages. Clients resume
+// with the since query param.
+func (a *api) streamEventsWebSocket(w http.ResponseWriter, r *http.Request) {
+	since, types, ok := parseStreamParams(w, r)
+	if !ok {
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader already replied to the client
+		log.Debugw("cannot upgrade to websocket", "err", err)
+		return
+	}
+	defer conn.Close()
+
+	backlog, ch, cancel, complete := a.checker.events.Subscribe(since, STREAM_BUFFER)
+	defer cancel()
+
+	// the read loop handles the control messages and detects closed connections
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	write := func(e Event) bool {
+		conn.SetWriteDeadline(time.Now().Add(STREAM_WRITE_TIMEOUT))
+		if err := conn.WriteJSON(e); err != nil {
+			log.Debugw("cannot write to websocket", "err", err)
+			return false
+		}
+		return true
+	}
+
+	if !complete && !write(resyncEvent(a.checker.events.Seq())) {
+		return
+	}
+	for _, e := range backlog {
+		if matchesTypes(e, types) && !write(e) {
+			return
+		}
+	}
+
+	keepalive := time.NewTicker(STREAM_KEEPALIVE)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case e, ok := <-ch:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "lagging behind"), time.Now().Add(STREAM_WRITE_TIMEOUT))
+				return
+			}
+			if matchesTypes(e, types) && !write(e) {
+				return
+			}
+		case <-keepalive.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(STREAM_WRITE_TIMEOUT)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// parseStreamParams reads the sequence number to resume from and the types of events to stream,
+// nil meaning all of them
+func parseStreamParams(w http.ResponseWriter, r *http.Request) (uint64, map[string]bool, bool) {
+	since, ok := parseUint(w, r, "since", 0)
+	if !ok {
+		return 0, nil, false
+	}
+
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		seq, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, API_ERR_BAD_REQUEST, fmt.Sprintf("invalid Last-Event-ID: %s", err))
+			return 0, nil, false
+		}
+		since = seq
+	}
+
+	var types map[string]bool
+	if s := r.URL.Query().Get("types"); s != "" {
+		types = make(map[string]bool)
+		for _, t := range strings.Split(s, ",") {
+			types[strings.TrimSpace(t)] = true
+		}
+	}
+
+	return since, types, true
+}
+
+func matchesTypes(e Event, types map[string]bool) bool {
+	return types == nil || types[e.Type]
+}
+
+// resyncEvent carries the current sequence number so that clients can resume from it once
+// they have reloaded the state
+func resyncEvent(seq uint64) Event {
+	return Event{Seq: seq, Type: EVENT_RESYNC, Timestamp: time.Now().Unix()}
+}
+
+func writeSSE(w http.ResponseWriter, e Event) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		log.Errorw("cannot encode event", "err", err)
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, data)
+}
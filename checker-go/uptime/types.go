@@ -51,22 +51,81 @@ type Votes struct {
 /// NOTE: This is an initial proposal, each checker could
 /// include different (an arbitrary types of) information.
 type HealtcheckInfo struct {
-    HealtcheckAddr MultiAddr
+    HealtcheckAddr MultiAddr `json:"healthcheckAddr"`
 
-    AvgLatency uint64
-    LatencyCounts uint64
+    // Cumulative average and number of the latencies of online probes
+    AvgLatency uint64 `json:"avgLatency"`
+    LatencyCounts uint64 `json:"latencyCounts"`
 
-    IsOnline bool
-    Latency uint64
-    LastChecked uint64
+    // Exponentially weighted moving average of the latency
+    EwmaLatency uint64 `json:"ewmaLatency"`
+
+    // Latency statistics over the sliding window of the latest probes
+    MinLatency uint64 `json:"minLatency"`
+    MaxLatency uint64 `json:"maxLatency"`
+    P50Latency uint64 `json:"p50Latency"`
+    P95Latency uint64 `json:"p95Latency"`
+    P99Latency uint64 `json:"p99Latency"`
+
+    // Ratio of online probes over the sliding window
+    SuccessRatio float64 `json:"successRatio"`
+
+    IsOnline bool `json:"isOnline"`
+    Latency uint64 `json:"latency"`
+    LastChecked uint64 `json:"lastChecked"`
+
+    // Status code and response body size of the last http healthcheck
+    StatusCode int `json:"statusCode"`
+    BodySize uint64 `json:"bodySize"`
+
+    // Percentage of online probes over the rolling windows
+    Uptime UptimeInfo `json:"uptime"`
+
+    latencySum uint64
+    latencyHigh bool // whether the latency is above the threshold of the latency events
+    window *latencyWindow
+    availability *availabilityTracker
+}
+
+/// Aggregated health information of a member node. The member is
+/// considered online if any of its healthcheck addresses is online.
+type MemberHealthInfo struct {
+    IsOnline bool `json:"isOnline"`
+    LastChecked uint64 `json:"lastChecked"`
+
+    // Percentage of rounds in which the member was online over the rolling windows
+    Uptime UptimeInfo `json:"uptime"`
+
+    Addresses map[MultiAddr]HealtcheckInfo `json:"addresses"`
+
+    availability *availabilityTracker
 }
 
 type UpInfo struct {
+    addr MultiAddr // the probed address
     isOnline bool
     latency uint64
     checkedTime uint64
+
+    // only populated by http healthchecks
+    statusCode int
+    bodySize uint64
+
+    // protocol of the prober used and, for offline nodes, the reason of the failure
+    protocol string
+    errReason string
+}
+
+func newMemberHealthInfo() MemberHealthInfo {
+	return MemberHealthInfo{
+		Addresses: make(map[MultiAddr]HealtcheckInfo),
+		availability: newAvailabilityTracker(),
+	}
 }
 
-type PeerReportPayload struct {
-    Checker ActorID `json:"checker"`
+func newHealtcheckInfo(addr MultiAddr) HealtcheckInfo {
+	return HealtcheckInfo{
+		HealtcheckAddr: addr,
+		availability: newAvailabilityTracker(),
+	}
 }
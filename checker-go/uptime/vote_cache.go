@@ -0,0 +1,77 @@
+package uptime
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+const DEFAULT_VOTE_CACHE_SIZE = 1024           // max number of votes remembered
+const DEFAULT_VOTE_CACHE_TTL = 30 * time.Minute // after which a vote is checked in the actor again
+
+// voteCache remembers the checkers this checker has already voted for, so that the actor
+// is not asked again and no report is sent that the actor would reject with AlreadyVoted.
+// It is shared by the successive states of the actor and invalidated by their diffs.
+type voteCache struct {
+	lru *simplelru.LRU // checker actor id to the time of the vote
+	ttl time.Duration
+
+	lock sync.Mutex
+}
+
+func newVoteCache(size int, ttl time.Duration) *voteCache {
+	// only fails on a non positive size
+	lru, err := simplelru.NewLRU(size, nil)
+	if err != nil {
+		panic(err)
+	}
+	return &voteCache{lru: lru, ttl: ttl}
+}
+
+// has returns whether the vote for the checker is known and not expired
+func (c *voteCache) has(checker ActorID) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	v, ok := c.lru.Get(checker)
+	if !ok {
+		return false
+	}
+	if time.Since(v.(time.Time)) > c.ttl {
+		c.lru.Remove(checker)
+		return false
+	}
+	return true
+}
+
+func (c *voteCache) add(checker ActorID) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.lru.Add(checker, time.Now())
+}
+
+func (c *voteCache) purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.lru.Purge()
+}
+
+// invalidate forgets the votes that the changes of the state void: a new round of votes
+// started, the checker is no longer reported, or the checker was removed or registered again.
+// New votes of other checkers keep the vote, which may not have landed in the state yet
+func (c *voteCache) invalidate(d *StateDiff) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, change := range d.OfflineCheckers {
+		if change.Type == VOTES_RESET || change.Type == VOTES_REMOVED {
+			c.lru.Remove(change.Checker)
+		}
+	}
+	for _, change := range d.Checkers {
+		if change.Type == CHANGE_ADDED || change.Type == CHANGE_REMOVED {
+			c.lru.Remove(change.Actor)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package uptime
+
+import (
+	"testing"
+)
+
+func TestVoteCacheInvalidate(t *testing.T) {
+	const voter = ActorID(100)
+	const other = ActorID(300)
+
+	cases := []struct {
+		name string
+		diff StateDiff
+		kept bool
+	}{
+		{
+			name: "votes of other checkers",
+			diff: StateDiff{OfflineCheckers: []VotesChange{{
+				Checker: testMember, Type: VOTES_ADDED,
+				After: &Votes{Votes: []ActorID{other}}, NewVoters: []ActorID{other},
+			}}},
+			kept: true,
+		},
+		{
+			name: "own vote landed",
+			diff: StateDiff{OfflineCheckers: []VotesChange{{
+				Checker: testMember, Type: VOTES_ADDED,
+				After: &Votes{Votes: []ActorID{voter}}, NewVoters: []ActorID{voter},
+			}}},
+			kept: true,
+		},
+		{
+			name: "checker edited",
+			diff: StateDiff{Checkers: []NodeChange{{Actor: testMember, Type: CHANGE_EDITED}}},
+			kept: true,
+		},
+		{
+			name: "votes reset",
+			diff: StateDiff{OfflineCheckers: []VotesChange{{Checker: testMember, Type: VOTES_RESET}}},
+		},
+		{
+			name: "votes removed",
+			diff: StateDiff{OfflineCheckers: []VotesChange{{Checker: testMember, Type: VOTES_REMOVED}}},
+		},
+		{
+			name: "checker removed",
+			diff: StateDiff{Checkers: []NodeChange{{Actor: testMember, Type: CHANGE_REMOVED}}},
+		},
+		{
+			name: "checker added",
+			diff: StateDiff{Checkers: []NodeChange{{Actor: testMember, Type: CHANGE_ADDED}}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			votes := newVoteCache(DEFAULT_VOTE_CACHE_SIZE, DEFAULT_VOTE_CACHE_TTL)
+			votes.add(testMember)
+
+			votes.invalidate(&c.diff)
+			if votes.has(testMember) != c.kept {
+				t.Errorf("vote kept %v, want %v", votes.has(testMember), c.kept)
+			}
+		})
+	}
+}
@@ -1,10 +1,42 @@
 package uptime
 
+// Mirror the constants of the actor
+const DEFAULT_VOTING_DURATION = 200      // epochs, used when the actor is created without one
+const VOTING_THRESHOLD_NUMERATOR = 20000 // the eviction threshold is 2/3 of the total checkers
+const VOTING_THRESHOLD_DENOMINATOR = 30000
+
 func (v *Votes) HasVoted(voter ActorID) (bool, error) {
-	for _, item := range(v.Votes) {
+	for _, item := range v.Votes {
 		if item == voter {
 			return true, nil
 		}
 	}
 	return false, nil
-}
\ No newline at end of file
+}
+
+// StartsNewRound returns whether the actor would drop the votes and start a new round for a
+// vote cast at the epoch. It mirrors Votes::within_threshold, which as written resets the
+// round while it is no older than votingDuration epochs.
+func (v *Votes) StartsNewRound(epoch ChainEpoch, votingDuration ChainEpoch) bool {
+	return !(v.LastVote+votingDuration < epoch)
+}
+
+// VotesRemaining returns the number of votes still needed to evict the checker
+func (v *Votes) VotesRemaining(totalCheckers uint64) uint64 {
+	needed := VotesNeeded(totalCheckers)
+	if uint64(len(v.Votes)) >= needed {
+		return 0
+	}
+	return needed - uint64(len(v.Votes))
+}
+
+// VotingThreshold mirrors calculate_voting_threshold in the actor: a checker is evicted
+// once its votes exceed it
+func VotingThreshold(totalCheckers uint64) uint64 {
+	return totalCheckers * VOTING_THRESHOLD_NUMERATOR / VOTING_THRESHOLD_DENOMINATOR
+}
+
+// VotesNeeded returns the number of votes that evicts a checker
+func VotesNeeded(totalCheckers uint64) uint64 {
+	return VotingThreshold(totalCheckers) + 1
+}
@@ -0,0 +1,78 @@
+package uptime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/api/v0api"
+	chainTypes "github.com/filecoin-project/lotus/chain/types"
+)
+
+var ErrWalletNotFound = errors.New("wallet not found in the node")
+var ErrWalletCannotSign = errors.New("wallet address cannot sign messages")
+var ErrWalletActorMismatch = errors.New("wallet does not resolve to the actor id")
+
+// ResolveWallet returns the wallet the messages are signed with, the from address if set,
+// otherwise the wallet at the index of WalletList. The order of WalletList is not stable,
+// the index is only kept for compatibility.
+func ResolveWallet(ctx context.Context, api v0api.FullNode, from string, index int) (address.Address, error) {
+	if from == "" {
+		log.Warnw("no wallet address set, using the wallet index, which may change with the wallets of the node", "index", index)
+		return getWalletAddressFromIndex(api, ctx, index)
+	}
+	return address.NewFromString(from)
+}
+
+// ValidateWallet checks that the wallet is a key of the node, so that it can sign messages
+func ValidateWallet(ctx context.Context, api v0api.FullNode, wallet address.Address) error {
+	// only key addresses sign messages, the node signs with the key it holds for them
+	switch wallet.Protocol() {
+	case address.SECP256K1, address.BLS:
+	default:
+		return fmt.Errorf("%w: %s", ErrWalletCannotSign, wallet)
+	}
+
+	has, err := api.WalletHas(ctx, wallet)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return fmt.Errorf("%w: %s", ErrWalletNotFound, wallet)
+	}
+	return nil
+}
+
+// LookupWalletActor returns the actor id of the wallet, which must be on chain
+func LookupWalletActor(ctx context.Context, api v0api.FullNode, wallet address.Address) (ActorID, error) {
+	idAddr, err := api.StateLookupID(ctx, wallet, chainTypes.EmptyTSK)
+	if err != nil {
+		return 0, fmt.Errorf("cannot lookup the id of wallet %s: %w", wallet, err)
+	}
+	return address.IDFromAddress(idAddr)
+}
+
+// CheckWalletActor checks that the id address of the wallet is the actor, so that the
+// messages are not signed with another identity
+func CheckWalletActor(ctx context.Context, api v0api.FullNode, wallet address.Address, actor ActorID) error {
+	id, err := LookupWalletActor(ctx, api, wallet)
+	if err != nil {
+		return err
+	}
+	if id != actor {
+		return fmt.Errorf("%w: %s is actor %d, not %d", ErrWalletActorMismatch, wallet, id, actor)
+	}
+	return nil
+}
+
+func getWalletAddressFromIndex(api v0api.FullNode, ctx context.Context, index int) (address.Address, error) {
+	walletList, err := api.WalletList(ctx)
+	if err != nil {
+		return address.Undef, err
+	}
+	if index < 0 || index >= len(walletList) {
+		return address.Undef, fmt.Errorf("no wallet at index %d, the node has %d wallets", index, len(walletList))
+	}
+	return walletList[index], nil
+}
@@ -0,0 +1,78 @@
+package uptime
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/api/v0api"
+	chainTypes "github.com/filecoin-project/lotus/chain/types"
+)
+
+// walletNode is a node holding a single wallet, of the actor id
+type walletNode struct {
+	v0api.FullNode
+
+	wallet address.Address
+	id     ActorID
+}
+
+func (n *walletNode) WalletHas(ctx context.Context, wallet address.Address) (bool, error) {
+	return wallet == n.wallet, nil
+}
+
+func (n *walletNode) StateLookupID(ctx context.Context, wallet address.Address, tsk chainTypes.TipSetKey) (address.Address, error) {
+	if wallet != n.wallet {
+		return address.Undef, errors.New("actor not found")
+	}
+	return address.NewIDAddress(n.id)
+}
+
+func newWalletNode(t *testing.T) *walletNode {
+	wallet, err := address.NewSecp256k1Address([]byte("wallet public key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &walletNode{wallet: wallet, id: testSelf}
+}
+
+func TestCheckWalletActor(t *testing.T) {
+	node := newWalletNode(t)
+	ctx := context.Background()
+
+	if err := CheckWalletActor(ctx, node, node.wallet, testSelf); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckWalletActor(ctx, node, node.wallet, testMember); !errors.Is(err, ErrWalletActorMismatch) {
+		t.Fatalf("error %v, want %v", err, ErrWalletActorMismatch)
+	}
+}
+
+func TestCheckerRejectsWalletOfAnotherActor(t *testing.T) {
+	node := newWalletNode(t)
+
+	u, err := NewUptimeChecker(node, testActor, nil, testMember, 0, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.SetWallet(node.wallet)
+
+	if err := u.checkWallet(context.Background()); !errors.Is(err, ErrWalletActorMismatch) {
+		t.Fatalf("error %v, want %v", err, ErrWalletActorMismatch)
+	}
+
+	// resolved from the wallet when not set
+	u, err = NewUptimeChecker(node, testActor, nil, 0, 0, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.SetWallet(node.wallet)
+
+	if err := u.checkWallet(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if u.self != testSelf {
+		t.Errorf("actor id %d, want %d", u.self, testSelf)
+	}
+}
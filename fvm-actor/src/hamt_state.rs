@@ -179,3 +179,33 @@ impl LoadableState for HamtState {
         Ok(cid)
     }
 }
+
+/// Writes the encoded state the Go checker decodes to its testdata, see
+/// checker-go/uptime/state_decoder_test.go. Run with
+/// `cargo test write_state_vectors -- --ignored` after changing the state.
+#[cfg(test)]
+mod state_vectors {
+    use super::*;
+    use multihash::MultihashDigest;
+
+    const TESTDATA: &str = "../checker-go/uptime/testdata";
+
+    fn test_cid(name: &str) -> Cid {
+        Cid::new_v1(DAG_CBOR, Code::Blake2b256.digest(name.as_bytes()))
+    }
+
+    /// Writes the state of the actor as stored on chain, for the golden tests of the decoder
+    /// of the checkers
+    #[test]
+    #[ignore]
+    fn write_state_vectors() {
+        let state = HamtState {
+            members: test_cid("members"),
+            checkers: test_cid("checkers"),
+            offline_checkers: test_cid("offline_checkers"),
+            total_checkers: 3,
+            voting_duration: 150,
+        };
+        std::fs::write(format!("{}/state_v1_map.cbor", TESTDATA), to_vec(&state).unwrap()).unwrap();
+    }
+}